@@ -0,0 +1,220 @@
+// Package packcheck defines an analyzer that checks pack and unpack calls with constant format strings.
+//
+// It reports what would otherwise only show up at run time: unknown format codes,
+// '*' on codes that ignore it and other format warnings, too few or unused arguments,
+// and arguments of types pack can not convert.
+package packcheck
+
+import (
+	"errors"
+	"github.com/xycczZ/php_pack/pack"
+	"github.com/xycczZ/php_pack/target"
+	"github.com/xycczZ/php_pack/unpack"
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+const doc = `check pack and unpack calls with constant format strings
+
+The format strings of pack.PHPPack, pack.NewOption, pack.Append, unpack.NewOption,
+the Compile functions and the encoders and decoders are checked for unknown codes
+and warnings. The arguments of pack calls are checked against the format: their number,
+and whether pack can convert their types.`
+
+// Analyzer checks pack and unpack calls.
+var Analyzer = &analysis.Analyzer{
+	Name:     "packcheck",
+	Doc:      doc,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+const (
+	packPath   = "github.com/xycczZ/php_pack/pack"
+	unpackPath = "github.com/xycczZ/php_pack/unpack"
+)
+
+// call locates the format and the first packed argument of a function, args is -1 without arguments.
+type call struct {
+	format, args int
+}
+
+var packCalls = map[string]call{
+	"PHPPack":       {0, 1},
+	"NewOption":     {0, 1},
+	"Append":        {1, 2},
+	"Compile":       {0, -1},
+	"CompileConfig": {0, -1},
+	"NewEncoder":    {1, -1},
+	"Explain":       {0, -1},
+	"SizeOf":        {0, -1},
+}
+
+var unpackCalls = map[string]call{
+	"NewOption":     {0, -1},
+	"Compile":       {0, -1},
+	"CompileConfig": {0, -1},
+	"NewDecoder":    {1, -1},
+	"Explain":       {0, -1},
+	"Annotate":      {0, -1},
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	// the 64-bit codes only exist when int is 64-bit
+	platform := target.Platform{}
+	if pass.TypesSizes != nil && pass.TypesSizes.Sizeof(types.Typ[types.Int]) == 4 {
+		platform = target.I386
+	}
+
+	ins := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	ins.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+		ce := n.(*ast.CallExpr)
+		fn, ok := typeutil.Callee(pass.TypesInfo, ce).(*types.Func)
+		if !ok || fn.Pkg() == nil || fn.Type().(*types.Signature).Recv() != nil {
+			return
+		}
+
+		var calls map[string]call
+		switch fn.Pkg().Path() {
+		case packPath:
+			calls = packCalls
+		case unpackPath:
+			calls = unpackCalls
+		}
+		c, ok := calls[fn.Name()]
+		if !ok || c.format >= len(ce.Args) {
+			return
+		}
+
+		arg := ce.Args[c.format]
+		tv := pass.TypesInfo.Types[arg]
+		if tv.Value == nil || tv.Value.Kind() != constant.String {
+			return
+		}
+		format := constant.StringVal(tv.Value)
+		name := fn.Pkg().Name() + "." + fn.Name()
+
+		if fn.Pkg().Path() == unpackPath {
+			checkUnpack(pass, arg, name, format, platform)
+			return
+		}
+
+		if !checkPack(pass, arg, name, format, platform) {
+			return
+		}
+		// the arguments of a call with a spread slice are unknown
+		if c.args >= 0 && !ce.Ellipsis.IsValid() {
+			checkArgs(pass, ce, ce.Args[c.args:], name, format, platform)
+		}
+	})
+	return nil, nil
+}
+
+func checkUnpack(pass *analysis.Pass, arg ast.Expr, name, format string, platform target.Platform) {
+	if _, err := unpack.CompileConfig(format, unpack.Config{Strict: true, Platform: platform}); err != nil {
+		pass.Reportf(arg.Pos(), "%s format %q: %v", name, format, err)
+	}
+}
+
+// checkPack checks a pack format and reports whether its arguments can be checked.
+func checkPack(pass *analysis.Pass, arg ast.Expr, name, format string, platform target.Platform) bool {
+	if _, err := pack.CompileConfig(format, pack.Config{Strict: true, Platform: platform}); err != nil {
+		pass.Reportf(arg.Pos(), "%s format %q: %v", name, format, err)
+		return errors.Is(err, pack.ErrWarning)
+	}
+	return true
+}
+
+// checkArgs checks the number and the types of the arguments of a pack call the way pack resolves them.
+func checkArgs(pass *analysis.Pass, ce *ast.CallExpr, args []ast.Expr, name, format string, platform target.Platform) {
+	f, err := pack.CompileConfig(format, pack.Config{Platform: platform, OnWarning: func(pack.Warning) {}})
+	if err != nil {
+		return
+	}
+	fields, err := f.Explain()
+	if err != nil {
+		return
+	}
+
+	next := 0
+	for _, field := range fields {
+		n, toString, msg := field.Count, false, "too few arguments"
+		switch field.Code {
+		case 'x', 'X', '@':
+			continue
+		case 'a', 'A', 'Z', 'h', 'H':
+			n, toString, msg = 1, true, "not enough arguments"
+		default:
+			if n < 0 {
+				n = len(args) - next
+			}
+		}
+
+		for ; n > 0; n-- {
+			if next >= len(args) {
+				pass.Reportf(ce.Rparen, "%s format %q: type %c: %s", name, format, field.Code, msg)
+				return
+			}
+			checkType(pass, args[next], next, name, field.Code, toString)
+			next++
+		}
+	}
+
+	if next < len(args) {
+		pass.Reportf(args[next].Pos(), "%s format %q: %d arguments unused", name, format, len(args)-next)
+	}
+}
+
+// checkType reports an argument pack can not convert to a string (a, A, Z, h, H) or a number.
+func checkType(pass *analysis.Pass, arg ast.Expr, index int, name string, code byte, toString bool) {
+	t := pass.TypesInfo.TypeOf(arg)
+	if t == nil || convertible(t, toString) {
+		return
+	}
+
+	to := "a number"
+	if toString {
+		to = "a string"
+	}
+	pass.Reportf(arg.Pos(), "%s argument %d: type %c can not convert %s to %s", name, index, code, t, to)
+}
+
+// convertible reports whether phpconv converts values of type t, interfaces are assumed to.
+func convertible(t types.Type, toString bool) bool {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		return u.Info()&types.IsComplex == 0 && u.Kind() != types.UnsafePointer
+	case *types.Interface:
+		return true
+	case *types.Slice:
+		if !toString {
+			// a php array
+			return true
+		}
+		elem, ok := u.Elem().Underlying().(*types.Basic)
+		return ok && elem.Kind() == types.Byte
+	case *types.Struct:
+		return toString && isStringer(t)
+	}
+	return false
+}
+
+// isStringer reports whether the values of t have a String() string method.
+func isStringer(t types.Type) bool {
+	obj, _, _ := types.LookupFieldOrMethod(t, false, nil, "String")
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return false
+	}
+	sig := fn.Type().(*types.Signature)
+	if sig.Params().Len() != 0 || sig.Results().Len() != 1 {
+		return false
+	}
+	res, ok := sig.Results().At(0).Type().(*types.Basic)
+	return ok && res.Kind() == types.String
+}
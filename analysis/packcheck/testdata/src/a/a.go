@@ -0,0 +1,58 @@
+package a
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/xycczZ/php_pack/pack"
+	"github.com/xycczZ/php_pack/unpack"
+)
+
+type id int
+
+type point struct{ X, Y int }
+
+type name struct{ s string }
+
+func (n name) String() string { return n.s }
+
+const header = "NC"
+
+func calls(args []any, format string, v any) {
+	pack.PHPPack("nvc*", 0x1234, 0x5678, 65, 66)
+	pack.PHPPack(header, 1, 2)
+	pack.PHPPack("a*x2A3", "one", []byte("two"), name{"three"}) // want `pack.PHPPack format "a\*x2A3": 1 arguments unused`
+	pack.PHPPack("C3", id(1), 2.5, true)
+	pack.PHPPack("N*", args...)
+	pack.PHPPack(format, 1)
+	pack.PHPPack("C", v)
+	pack.PHPPack("Y", 1)         // want `pack.PHPPack format "Y": type Y: unknown format code`
+	pack.PHPPack("Cx*", 1)       // want `pack.PHPPack format "Cx\*": type x: '\*' ignored`
+	pack.PHPPack("Cx*", 1, 2)    // want `pack.PHPPack format "Cx\*": type x: '\*' ignored` `pack.PHPPack format "Cx\*": 1 arguments unused`
+	pack.PHPPack("N2", 1)        // want `pack.PHPPack format "N2": type N: too few arguments`
+	pack.PHPPack("Na", 1)        // want `pack.PHPPack format "Na": type a: not enough arguments`
+	pack.PHPPack("C", 1, 2, 3)   // want `pack.PHPPack format "C": 2 arguments unused`
+	pack.PHPPack("C", point{})   // want `pack.PHPPack argument 0: type C can not convert a.point to a number`
+	pack.PHPPack("a*", point{})  // want `pack.PHPPack argument 0: type a can not convert a.point to a string`
+	pack.PHPPack("C", name{})    // want `pack.PHPPack argument 0: type C can not convert a.name to a number`
+	pack.PHPPack("a*", []int{1}) // want `pack.PHPPack argument 0: type a can not convert \[\]int to a string`
+	pack.PHPPack("N", []int{1})
+	pack.PHPPack("Cd", 1, complex(1, 2)) // want `pack.PHPPack argument 1: type d can not convert complex128 to a number`
+	pack.PHPPack("C", &point{})          // want `pack.PHPPack argument 0: type C can not convert \*a.point to a number`
+	pack.PHPPack("C", map[string]int{})  // want `pack.PHPPack argument 0: type C can not convert map\[string\]int to a number`
+	pack.Append(nil, "n", 1, 2)          // want `pack.Append format "n": 1 arguments unused`
+	pack.NewOption("q", 1, 2)            // want `pack.NewOption format "q": 1 arguments unused`
+	pack.Compile("Z")
+	pack.CompileConfig("W", pack.Config{}) // want `pack.CompileConfig format "W": type W: unknown format code`
+	pack.NewEncoder(&bytes.Buffer{}, "@*") // want `pack.NewEncoder format "@\*": type @: '\*' ignored`
+	pack.SizeOf("N2n")
+
+	fmt.Sprintf("Y%d", 1)
+
+	f, _ := pack.Compile("C")
+	f.Pack(1, 2)
+
+	unpack.PHPUnpack(unpack.NewOption("Nlen/a*data", nil))
+	unpack.NewOption("Nlen/Ydata", nil)     // want `unpack.NewOption format "Nlen/Ydata": type Y: unknown format code`
+	unpack.NewDecoder(&bytes.Buffer{}, "Y") // want `unpack.NewDecoder format "Y": type Y: unknown format code`
+	unpack.Annotate("C2x/Y", nil)           // want `unpack.Annotate format "C2x/Y": type Y: unknown format code`
+}
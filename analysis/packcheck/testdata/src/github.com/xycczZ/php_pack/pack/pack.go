@@ -0,0 +1,19 @@
+// Package pack stubs the functions packcheck checks.
+package pack
+
+import "io"
+
+type Config struct{}
+type Encoder struct{}
+type Format struct{}
+type Option struct{}
+
+func PHPPack(format string, args ...any) ([]byte, error)            { return nil, nil }
+func Append(dst []byte, format string, args ...any) ([]byte, error) { return nil, nil }
+func NewOption(format string, args ...any) *Option                  { return nil }
+func Compile(format string) (*Format, error)                        { return nil, nil }
+func CompileConfig(format string, config Config) (*Format, error)   { return nil, nil }
+func NewEncoder(w io.Writer, format string) (*Encoder, error)       { return nil, nil }
+func SizeOf(format string) (int, error)                             { return 0, nil }
+
+func (f *Format) Pack(args ...any) ([]byte, error) { return nil, nil }
@@ -0,0 +1,12 @@
+// Package unpack stubs the functions packcheck checks.
+package unpack
+
+import "io"
+
+type Decoder struct{}
+type Option struct{}
+
+func NewOption(format string, val []byte) *Option             { return nil }
+func PHPUnpack(option *Option) (map[string]any, error)        { return nil, nil }
+func NewDecoder(r io.Reader, format string) (*Decoder, error) { return nil, nil }
+func Annotate(format string, data []byte) (string, error)     { return "", nil }
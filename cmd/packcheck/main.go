@@ -0,0 +1,15 @@
+// Packcheck checks the pack and unpack calls of Go packages, see package packcheck.
+//
+// It runs standalone, packcheck ./..., or through go vet:
+//
+//	go vet -vettool=$(which packcheck) ./...
+package main
+
+import (
+	"github.com/xycczZ/php_pack/analysis/packcheck"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(packcheck.Analyzer)
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/xycczZ/php_pack/phparray"
+	"github.com/xycczZ/php_pack/phpconv"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// decode decodes input given as raw bytes, hex, base64 or an xxd dump.
+// Whitespace is ignored in hex and base64.
+func decode(encoding string, input []byte) ([]byte, error) {
+	switch encoding {
+	case "raw":
+		return input, nil
+	case "hex":
+		return hex.DecodeString(string(removeSpace(input)))
+	case "base64":
+		return base64.StdEncoding.DecodeString(string(removeSpace(input)))
+	case "xxd":
+		return decodeXXD(input)
+	}
+	return nil, fmt.Errorf("unknown input encoding %q, want raw, hex, base64 or xxd", encoding)
+}
+
+func removeSpace(b []byte) []byte {
+	return bytes.Join(bytes.Fields(b), nil)
+}
+
+// decodeXXD reads the output of xxd: "00000000: 4865 6c6c 6f0a  Hello.",
+// the hex columns sit between the offset and two spaces before the text column.
+func decodeXXD(input []byte) ([]byte, error) {
+	var out []byte
+	for n, line := range strings.Split(string(input), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		_, rest, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("xxd line %d: missing offset", n+1)
+		}
+		rest = strings.TrimPrefix(rest, " ")
+		if i := strings.Index(rest, "  "); i >= 0 {
+			rest = rest[:i]
+		}
+
+		b, err := hex.DecodeString(strings.ReplaceAll(rest, " ", ""))
+		if err != nil {
+			return nil, fmt.Errorf("xxd line %d: %w", n+1, err)
+		}
+		out = append(out, b...)
+	}
+	return out, nil
+}
+
+// encodeString writes a binary string as raw bytes, hex, base64, a JSON string or var_dump text.
+func encodeString(encoding string, s []byte) ([]byte, error) {
+	switch encoding {
+	case "raw":
+		return s, nil
+	case "hex":
+		return []byte(hex.EncodeToString(s) + "\n"), nil
+	case "base64":
+		return []byte(base64.StdEncoding.EncodeToString(s) + "\n"), nil
+	case "json":
+		b, err := jsonString(string(s))
+		if err != nil {
+			return nil, err
+		}
+		return append(b, '\n'), nil
+	case "dump":
+		return []byte(fmt.Sprintf("string(%d) \"%s\"\n", len(s), s)), nil
+	}
+	return nil, fmt.Errorf("unknown output encoding %q, want raw, hex, base64, json or dump", encoding)
+}
+
+// encodeArray writes an unpacked array as var_dump text, a JSON object,
+// or one "key: value" line per element with strings in raw, hex or base64.
+func encodeArray(encoding string, a *phparray.Array) ([]byte, error) {
+	switch encoding {
+	case "dump":
+		return []byte(a.Dump()), nil
+	case "json":
+		return encodeJSON(a)
+	case "raw", "hex", "base64":
+	default:
+		return nil, fmt.Errorf("unknown output encoding %q, want dump, json, raw, hex or base64", encoding)
+	}
+
+	var out []byte
+	var err error
+	a.Range(func(k phparray.Key, v any) bool {
+		out = append(out, k.String()...)
+		out = append(out, ": "...)
+		if s, ok := v.([]byte); ok {
+			var b []byte
+			if b, err = encodeString(encoding, s); err != nil {
+				return false
+			}
+			out = append(out, b...)
+			if encoding == "raw" {
+				out = append(out, '\n')
+			}
+			return true
+		}
+		out = append(out, scalar(v)...)
+		out = append(out, '\n')
+		return true
+	})
+	return out, err
+}
+
+// encodeJSON writes a like json_encode without flags: an object in key order, strings
+// escaped by jsonString, and NaN, INF or a string that is not valid UTF-8 is an error.
+func encodeJSON(a *phparray.Array) ([]byte, error) {
+	var out bytes.Buffer
+	var err error
+	out.WriteByte('{')
+	i := 0
+	a.Range(func(k phparray.Key, v any) bool {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		i++
+
+		key, kerr := jsonString(k.String())
+		if kerr != nil {
+			err = fmt.Errorf("%s: %w", k, kerr)
+			return false
+		}
+		out.Write(key)
+		out.WriteByte(':')
+
+		switch x := v.(type) {
+		case []byte:
+			b, serr := jsonString(string(x))
+			if serr != nil {
+				err = fmt.Errorf("%s: %w", k, serr)
+				return false
+			}
+			out.Write(b)
+		case float64:
+			if math.IsNaN(x) || math.IsInf(x, 0) {
+				err = fmt.Errorf("%s: Inf and NaN cannot be JSON encoded", k)
+				return false
+			}
+			out.WriteString(strings.Replace(phpconv.FormatFloat(x, phpconv.SerializePrecision), "E", "e", 1))
+		default:
+			out.WriteString(scalar(v))
+		}
+		return true
+	})
+	out.WriteString("}\n")
+	return out.Bytes(), err
+}
+
+// errMalformedUTF8 is the json_last_error_msg() of a string that is not valid UTF-8.
+var errMalformedUTF8 = errors.New("Malformed UTF-8 characters, possibly incorrectly encoded")
+
+// jsonString quotes s like json_encode without flags: '/' is escaped as \/, control and
+// non-ASCII characters as \uXXXX (UTF-16 surrogate pairs above U+FFFF), <, > and & are
+// left alone. A string that is not valid UTF-8 is an error, json_encode returns false for it.
+func jsonString(s string) ([]byte, error) {
+	if !utf8.ValidString(s) {
+		return nil, errMalformedUTF8
+	}
+
+	const digits = "0123456789abcdef"
+	b := make([]byte, 0, len(s)+2)
+	b = append(b, '"')
+	for _, r := range s {
+		switch r {
+		case '"', '\\', '/':
+			b = append(b, '\\', byte(r))
+		case '\b':
+			b = append(b, '\\', 'b')
+		case '\f':
+			b = append(b, '\\', 'f')
+		case '\n':
+			b = append(b, '\\', 'n')
+		case '\r':
+			b = append(b, '\\', 'r')
+		case '\t':
+			b = append(b, '\\', 't')
+		default:
+			if r >= 0x20 && r < utf8.RuneSelf {
+				b = append(b, byte(r))
+				continue
+			}
+			for _, u := range utf16.Encode([]rune{r}) {
+				b = append(b, '\\', 'u', digits[u>>12], digits[u>>8&0xf], digits[u>>4&0xf], digits[u&0xf])
+			}
+		}
+	}
+	return append(b, '"'), nil
+}
+
+// scalar formats an unpacked int or float.
+func scalar(v any) string {
+	switch x := v.(type) {
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case float64:
+		return phpconv.FormatFloat(x, phpconv.SerializePrecision)
+	}
+	return fmt.Sprint(v)
+}
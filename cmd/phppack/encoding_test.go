@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	cases := []struct {
+		Encoding string
+		Input    string
+		Expected []byte
+	}{
+		{"raw", "ab", []byte("ab")},
+		{"hex", "00ff\n10 20", []byte{0, 0xff, 0x10, 0x20}},
+		{"base64", "AAEC\n", []byte{0, 1, 2}},
+		{"xxd", "00000000: 0001 0203 0405 0607 0809 0a0b 0c0d 0e0f  ................\n" +
+			"00000010: 2020                                       \n", []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, ' ', ' '}},
+	}
+
+	for _, c := range cases {
+		b, err := decode(c.Encoding, []byte(c.Input))
+		if err != nil {
+			t.Errorf("decode %s failed: %v\n", c.Encoding, err)
+		} else if !bytes.Equal(b, c.Expected) {
+			t.Errorf("decode %s error, expected: %v, actual: %v\n", c.Encoding, c.Expected, b)
+		}
+	}
+
+	for _, c := range []struct{ Encoding, Input string }{
+		{"hex", "0g"},
+		{"base64", "A"},
+		{"xxd", "0001 0203"},
+		{"utf16", ""},
+	} {
+		if _, err := decode(c.Encoding, []byte(c.Input)); err == nil {
+			t.Errorf("decode %s of %q should fail\n", c.Encoding, c.Input)
+		}
+	}
+}
+
+func TestJSONString(t *testing.T) {
+	// the expected values are json_encode() without flags
+	cases := []struct {
+		Input    string
+		Expected string
+	}{
+		{"a/b", `"a\/b"`},
+		{"é", `"\u00e9"`},
+		{"<&'>", `"<&'>"`},
+		{"\"\\", `"\"\\"`},
+		{"\b\f\n\r\t\x00\x1f\x7f", `"\b\f\n\r\t\u0000\u001f` + "\x7f" + `"`},
+		{"€😀", `"\u20ac\ud83d\ude00"`},
+	}
+
+	for _, c := range cases {
+		b, err := jsonString(c.Input)
+		if err != nil || string(b) != c.Expected {
+			t.Errorf("json string %q error, expected: %s, actual: %s %v\n", c.Input, c.Expected, b, err)
+		}
+	}
+
+	if _, err := jsonString("\xff"); err != errMalformedUTF8 {
+		t.Errorf("json string of invalid UTF-8 error, expected: %v, actual: %v\n", errMalformedUTF8, err)
+	}
+}
@@ -0,0 +1,322 @@
+// Command phppack packs and unpacks binary strings like php's pack() and unpack().
+//
+//	phppack pack [flags] FORMAT [ARG...]
+//	phppack unpack [flags] FORMAT [DATA]
+//
+// pack takes its arguments from the command line, or one per line from -in.
+// Arguments are strings converted the way php converts them, so "12" packs as the integer 12.
+// unpack reads DATA, or -in, or stdin, encoded as -from raw, hex, base64 or xxd.
+//
+// The result is written as raw bytes, hex, base64, JSON or php's var_dump text (-to),
+// warnings are written to stderr. unpack -to annotate prints a hex dump of the input
+// showing the field, key and value of every byte range instead.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/xycczZ/php_pack/pack"
+	"github.com/xycczZ/php_pack/target"
+	"github.com/xycczZ/php_pack/unpack"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+const usage = `usage: phppack pack [flags] FORMAT [ARG...]
+       phppack unpack [flags] FORMAT [DATA]
+run "phppack pack -h" or "phppack unpack -h" for the flags
+`
+
+var platforms = map[string]target.Platform{
+	"amd64":   target.AMD64,
+	"arm64":   target.ARM64,
+	"386":     target.I386,
+	"arm":     target.ARM,
+	"ppc64le": target.PPC64LE,
+	"s390x":   target.S390X,
+	"ppc64":   target.PPC64,
+	"mips":    target.MIPS,
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes a command line and returns the exit code:
+// 0 on success, 1 when packing or unpacking fails, 2 for usage errors.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	var err error
+	switch args[0] {
+	case "pack":
+		err = runPack(args[1:], stdin, stdout, stderr)
+	case "unpack":
+		err = runUnpack(args[1:], stdin, stdout, stderr)
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "phppack: unknown command %q\n%s", args[0], usage)
+		return 2
+	}
+
+	var ue *usageError
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.As(err, &ue):
+		fmt.Fprintf(stderr, "phppack: %v\n", err)
+		return 2
+	}
+	fmt.Fprintf(stderr, "phppack: %v\n", err)
+	return 1
+}
+
+// usageError is a bad command line.
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+// options are the flags shared by both commands.
+type options struct {
+	in       string
+	to       string
+	version  string
+	platform string
+	strict   bool
+}
+
+func (o *options) register(fs *flag.FlagSet, to string) {
+	fs.StringVar(&o.in, "in", "", `read from file, "-" for stdin`)
+	fs.StringVar(&o.to, "to", to, "output `encoding`: raw, hex, base64, json or dump")
+	fs.StringVar(&o.version, "php", "", "php `version` to behave like, e.g. 7.4")
+	fs.StringVar(&o.platform, "platform", "", "machine php runs on: "+strings.Join(platformNames(), ", "))
+	fs.BoolVar(&o.strict, "strict", false, "fail on warnings")
+}
+
+// config returns the pack and unpack config of the flags, warnings go to stderr.
+func (o *options) config(stderr io.Writer) (pack.Config, error) {
+	config := pack.Config{Strict: o.strict}
+	if !o.strict {
+		// strict mode reports the warning as the error
+		config.OnWarning = func(w pack.Warning) {
+			fmt.Fprintf(stderr, "Warning: %s\n", w)
+		}
+	}
+
+	if o.version != "" {
+		v, err := target.ParseVersion(o.version)
+		if err != nil {
+			return config, &usageError{err.Error()}
+		}
+		config.Version = v
+	}
+	if o.platform != "" {
+		p, ok := platforms[o.platform]
+		if !ok {
+			return config, &usageError{fmt.Sprintf("unknown platform %q, want %s", o.platform, strings.Join(platformNames(), ", "))}
+		}
+		config.Platform = p
+	}
+	return config, nil
+}
+
+// read reads the -in file, or stdin for "-".
+func (o *options) read(stdin io.Reader) ([]byte, error) {
+	if o.in == "-" {
+		return io.ReadAll(stdin)
+	}
+	return os.ReadFile(o.in)
+}
+
+func platformNames() []string {
+	names := make([]string, 0, len(platforms))
+	for name := range platforms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func newFlagSet(name string, stderr io.Writer, synopsis string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: phppack %s\n", synopsis)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func runPack(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	var o options
+	fs := newFlagSet("pack", stderr, "pack [flags] FORMAT [ARG...]")
+	o.register(fs, "hex")
+	if err := fs.Parse(args); err != nil {
+		return parseError(err)
+	}
+	if fs.NArg() < 1 {
+		fs.Usage()
+		return &usageError{"pack: missing format"}
+	}
+	if err := oneOf("-to", o.to, "raw", "hex", "base64", "json", "dump"); err != nil {
+		return err
+	}
+
+	config, err := o.config(stderr)
+	if err != nil {
+		return err
+	}
+
+	values := fs.Args()[1:]
+	if o.in != "" {
+		if len(values) > 0 {
+			return &usageError{"pack: arguments and -in are exclusive"}
+		}
+		data, err := o.read(stdin)
+		if err != nil {
+			return err
+		}
+		values = lines(string(data))
+	}
+
+	packArgs := make([]any, len(values))
+	for i, v := range values {
+		packArgs[i] = v
+	}
+
+	f, err := pack.CompileConfig(fs.Arg(0), config)
+	if err != nil {
+		return err
+	}
+	r, err := f.Pack(packArgs...)
+	if err != nil {
+		return err
+	}
+
+	out, err := encodeString(o.to, r)
+	if err != nil {
+		return err
+	}
+	_, err = stdout.Write(out)
+	return err
+}
+
+func runUnpack(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	var o options
+	var from string
+	var offset int
+	fs := newFlagSet("unpack", stderr, "unpack [flags] FORMAT [DATA]")
+	o.register(fs, "dump")
+	fs.Lookup("to").Usage = "output `encoding`: dump, json, raw, hex, base64, or annotate for a hex dump annotated with the fields"
+	fs.StringVar(&from, "from", "raw", "input `encoding`: raw, hex, base64 or xxd")
+	fs.IntVar(&offset, "offset", 0, "offset to start unpacking at")
+	if err := fs.Parse(args); err != nil {
+		return parseError(err)
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		return &usageError{"unpack: want a format and at most one DATA argument"}
+	}
+	if err := oneOf("-from", from, "raw", "hex", "base64", "xxd"); err != nil {
+		return err
+	}
+	if err := oneOf("-to", o.to, "dump", "json", "raw", "hex", "base64", "annotate"); err != nil {
+		return err
+	}
+
+	config, err := o.config(stderr)
+	if err != nil {
+		return err
+	}
+
+	var input []byte
+	switch {
+	case fs.NArg() == 2:
+		if o.in != "" {
+			return &usageError{"unpack: DATA and -in are exclusive"}
+		}
+		input = []byte(fs.Arg(1))
+	case o.in == "":
+		o.in = "-"
+		fallthrough
+	default:
+		if input, err = o.read(stdin); err != nil {
+			return err
+		}
+	}
+
+	data, err := decode(from, input)
+	if err != nil {
+		return err
+	}
+
+	f, err := unpack.CompileConfig(fs.Arg(0), config)
+	if err != nil {
+		return err
+	}
+	if o.to == "annotate" {
+		dump, err := f.Annotate(data, offset)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(stdout, dump)
+		return err
+	}
+
+	a, err := f.UnpackArray(data, offset)
+	if err != nil {
+		return err
+	}
+
+	out, err := encodeArray(o.to, a)
+	if err != nil {
+		return err
+	}
+	_, err = stdout.Write(out)
+	return err
+}
+
+// oneOf checks the value of an encoding flag.
+func oneOf(flag, value string, allowed ...string) error {
+	for _, a := range allowed {
+		if value == a {
+			return nil
+		}
+	}
+	return &usageError{fmt.Sprintf("unknown %s encoding %q, want %s", flag, value, strings.Join(allowed, ", "))}
+}
+
+// parseError reports flag errors, which the flag set has printed already, as usage errors.
+func parseError(err error) error {
+	if errors.Is(err, flag.ErrHelp) {
+		return err
+	}
+	return &usageError{err.Error()}
+}
+
+// lines splits text into lines, without the line endings and a final empty line.
+func lines(text string) []string {
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" {
+		return nil
+	}
+	ls := strings.Split(text, "\n")
+	for i, l := range ls {
+		ls[i] = strings.TrimSuffix(l, "\r")
+	}
+	return ls
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	argsFile := filepath.Join(t.TempDir(), "args")
+	if err := os.WriteFile(argsFile, []byte("1\r\n2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Args   []string
+		Stdin  string
+		Code   int
+		Stdout string
+		Stderr string
+	}{
+		{[]string{"pack", "c2n2", "52", "120", "65", "66"}, "", 0, "347800410042\n", ""},
+		{[]string{"pack", "-to", "base64", "N", "12abc"}, "", 0, "AAAADA==\n", ""},
+		{[]string{"pack", "-to", "raw", "a*", "hi"}, "", 0, "hi", ""},
+		{[]string{"pack", "-to", "dump", "A3", "x"}, "", 0, "string(3) \"x  \"\n", ""},
+		{[]string{"pack", "-to", "json", "a*", "<\"é/\">"}, "", 0, "\"<\\\"\\u00e9\\/\\\">\"\n", ""},
+		{[]string{"pack", "-to", "json", "C", "255"}, "", 1, "", "phppack: Malformed UTF-8 characters, possibly incorrectly encoded\n"},
+		{[]string{"pack", "-in", "-", "C*"}, "1\n2\n3\n", 0, "010203\n", ""},
+		{[]string{"pack", "-in", argsFile, "n*"}, "", 0, "00010002\n", ""},
+		{[]string{"pack", "-platform", "s390x", "s", "1"}, "", 0, "0001\n", ""},
+		{[]string{"pack", "C", "1", "2"}, "", 0, "01\n", "Warning: 1 arguments unused\n"},
+		{[]string{"pack", "-strict", "C", "1", "2"}, "", 1, "", "phppack: 1 arguments unused\n"},
+		{[]string{"pack", "-php", "5.4", "Z", "a"}, "", 1, "", "Warning: type Z: unknown format code\nphppack: type Z: unknown format code\n"},
+		{[]string{"pack", "N2", "1"}, "", 1, "", "phppack: type N: too few arguments\n"},
+		{[]string{"pack", "-to", "xml", "N", "1"}, "", 2, "", "phppack: unknown -to encoding \"xml\", want raw, hex, base64, json, dump\n"},
+
+		{[]string{"unpack", "-from", "hex", "c2chars/n2int", "3478 0041 0042"}, "", 0,
+			"array(4) {\n  [\"chars1\"]=>\n  int(52)\n  [\"chars2\"]=>\n  int(120)\n  [\"int1\"]=>\n  int(65)\n  [\"int2\"]=>\n  int(66)\n}\n", ""},
+		{[]string{"unpack", "-to", "json", "a2s/Cn"}, "ab\x01", 0, "{\"s\":\"ab\",\"n\":1}\n", ""},
+		{[]string{"unpack", "-from", "hex", "-to", "json", "a1s"}, "ff", 1, "", "phppack: s: Malformed UTF-8 characters, possibly incorrectly encoded\n"},
+		{[]string{"unpack", "-from", "base64", "-to", "hex", "a2s/C"}, "AAEC", 0, "s: 0001\n1: 2\n", ""},
+		{[]string{"unpack", "-from", "xxd", "-to", "raw", "a*"}, "00000000: 6869 0a                                hi.\n", 0, "1: hi\n\n", ""},
+		{[]string{"unpack", "-from", "hex", "-offset", "1", "C"}, "0102", 0, "array(1) {\n  [1]=>\n  int(2)\n}\n", ""},
+		{[]string{"unpack", "-from", "hex", "-to", "json", "E"}, "7ff0000000000000", 1, "", "phppack: 1: Inf and NaN cannot be JSON encoded\n"},
+		{[]string{"unpack", "-from", "hex", "N", "01"}, "", 1, "", "phppack: type N: not enough input, need 4, have 1\n"},
+		{[]string{"unpack", "-from", "hex", "-to", "annotate", "Nlen/a3s/n"}, "00000001616263ff", 0,
+			"offset    bytes                                            field\n" +
+				"00000000  00 00 00 01                                      N len = 1\n" +
+				"00000004  61 62 63                                         a3 s = \"abc\"\n" +
+				"00000007  ff                                               n <-- input ends, needs 2 bytes, 1 left\n", ""},
+		{[]string{"unpack", "-from", "zip", "C"}, "", 2, "", "phppack: unknown -from encoding \"zip\", want raw, hex, base64, xxd\n"},
+
+		{[]string{}, "", 2, "", usage},
+		{[]string{"repack"}, "", 2, "", "phppack: unknown command \"repack\"\n" + usage},
+	}
+
+	for _, c := range cases {
+		var stdout, stderr bytes.Buffer
+		code := run(c.Args, strings.NewReader(c.Stdin), &stdout, &stderr)
+		if code != c.Code || stdout.String() != c.Stdout || stderr.String() != c.Stderr {
+			t.Errorf("phppack %q error, expected: %d %q %q, actual: %d %q %q\n",
+				c.Args, c.Code, c.Stdout, c.Stderr, code, stdout.String(), stderr.String())
+		}
+	}
+}
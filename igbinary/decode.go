@@ -0,0 +1,365 @@
+package igbinary
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/xycczZ/php_pack/phparray"
+	"github.com/xycczZ/php_pack/phpserialize"
+	"math"
+	"slices"
+)
+
+// Unmarshal parses the igbinary payload data, see UnmarshalConfig.
+func Unmarshal(data []byte) (any, error) {
+	return UnmarshalConfig(data, Config{})
+}
+
+// UnmarshalConfig parses the igbinary payload data like igbinary_unserialize(), limited by config.
+//
+// References decode to the value they refer to: to an object or an array they share it,
+// to other values they copy it. Data after the value is an error.
+func UnmarshalConfig(data []byte, config Config) (any, error) {
+	if config.MaxDepth == 0 {
+		config.MaxDepth = phpserialize.DefaultMaxDepth
+	}
+	d := decoder{data: data, config: &config}
+
+	if len(data) < 5 {
+		return nil, d.error("payload of %d bytes, a header and a value need at least 5", len(data))
+	}
+	if version := binary.BigEndian.Uint32(data); version != 1 && version != 2 {
+		return nil, d.error("unsupported version %#x", version)
+	}
+	d.pos = 4
+
+	v, err := d.value(false)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos < len(data) {
+		return nil, d.error("extra data after the value")
+	}
+	return v, nil
+}
+
+type decoder struct {
+	data   []byte
+	pos    int
+	config *Config
+	// strings is the string table, refs are the arrays, objects and php references by their number
+	strings []string
+	refs    []any
+	values  int
+	depth   int
+}
+
+func (d *decoder) error(format string, args ...any) error {
+	return &SyntaxError{Offset: d.pos, Len: len(d.data), Msg: fmt.Sprintf(format, args...)}
+}
+
+// read returns the next n bytes.
+func (d *decoder) read(n int) ([]byte, error) {
+	if n > len(d.data)-d.pos {
+		return nil, d.error("unexpected end of input, need %d bytes, %d left", n, len(d.data)-d.pos)
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+// size reads an unsigned big-endian integer of 1, 2, 4 or 8 bytes.
+func (d *decoder) size(n int) (uint64, error) {
+	b, err := d.read(n)
+	if err != nil {
+		return 0, err
+	}
+	switch n {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
+
+// width returns the size of the 8, 16 and 32 bit variants of a type, t8 being the first.
+func width(t, t8 byte) int {
+	return 1 << (t - t8)
+}
+
+// value decodes the value at pos, a php reference (wantRef) is numbered whatever its type.
+func (d *decoder) value(wantRef bool) (any, error) {
+	start := d.pos
+	if d.pos >= len(d.data) {
+		return nil, d.error("unexpected end of input")
+	}
+	if d.config.MaxElements > 0 && d.values >= d.config.MaxElements {
+		return nil, d.error("more than %d values", d.config.MaxElements)
+	}
+	d.values++
+
+	t := d.data[d.pos]
+	d.pos++
+
+	var v any
+	var err error
+	switch t {
+	case typeNull:
+	case typeFalse, typeTrue:
+		v = t == typeTrue
+	case typeLong8p, typeLong8n, typeLong16p, typeLong16n, typeLong32p, typeLong32n, typeLong64p, typeLong64n:
+		v, err = d.long(t)
+	case typeDouble:
+		var bits uint64
+		bits, err = d.size(8)
+		v = math.Float64frombits(bits)
+	case typeStringEmpty, typeStringID8, typeStringID16, typeStringID32, typeString8, typeString16, typeString32:
+		var s string
+		s, err = d.string(t)
+		v = []byte(s)
+	case typeArray8, typeArray16, typeArray32:
+		return d.array(t)
+	case typeObject8, typeObject16, typeObject32, typeObjectID8, typeObjectID16, typeObjectID32:
+		return d.object(t)
+	case typeRef8, typeRef16, typeRef32:
+		return d.ref(width(t, typeRef8), false)
+	case typeObjRef8, typeObjRef16, typeObjRef32:
+		return d.ref(width(t, typeObjRef8), true)
+	case typeRef:
+		// igbinary writes a php reference once, a reference to a reference is never valid
+		if wantRef {
+			d.pos = start
+			return nil, d.error("reference to a reference")
+		}
+		d.values--
+		return d.value(true)
+	default:
+		d.pos = start
+		return nil, d.error("unknown type %#02x", t)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if wantRef {
+		d.refs = append(d.refs, v)
+	}
+	return v, nil
+}
+
+func (d *decoder) long(t byte) (int64, error) {
+	start := d.pos
+	var n int
+	switch t {
+	case typeLong8p, typeLong8n:
+		n = 1
+	case typeLong16p, typeLong16n:
+		n = 2
+	case typeLong32p, typeLong32n:
+		n = 4
+	default:
+		n = 8
+	}
+	k, err := d.size(n)
+	if err != nil {
+		return 0, err
+	}
+
+	if t == typeLong8n || t == typeLong16n || t == typeLong32n || t == typeLong64n {
+		if k > 1<<63 {
+			d.pos = start
+			return 0, d.error("negative integer out of range")
+		}
+		return int64(-k), nil
+	}
+	if k > math.MaxInt64 {
+		d.pos = start
+		return 0, d.error("integer out of range")
+	}
+	return int64(k), nil
+}
+
+// string reads a string, a new one is added to the string table.
+func (d *decoder) string(t byte) (string, error) {
+	switch t {
+	case typeStringEmpty:
+		return "", nil
+	case typeStringID8, typeStringID16, typeStringID32:
+		return d.stringID(width(t, typeStringID8))
+	}
+	return d.newString(width(t, typeString8))
+}
+
+func (d *decoder) stringID(n int) (string, error) {
+	start := d.pos
+	id, err := d.size(n)
+	if err != nil {
+		return "", err
+	}
+	if id >= uint64(len(d.strings)) {
+		d.pos = start
+		return "", d.error("string %d of %d", id, len(d.strings))
+	}
+	return d.strings[id], nil
+}
+
+func (d *decoder) newString(n int) (string, error) {
+	length, err := d.size(n)
+	if err != nil {
+		return "", err
+	}
+	if length > uint64(len(d.data)-d.pos) {
+		return "", d.error("string of %d bytes, %d left", length, len(d.data)-d.pos)
+	}
+	b, _ := d.read(int(length))
+	d.strings = append(d.strings, string(b))
+	return string(b), nil
+}
+
+// ref returns an earlier array, object or php reference, objref must refer to an object.
+func (d *decoder) ref(n int, objref bool) (any, error) {
+	start := d.pos
+	id, err := d.size(n)
+	if err != nil {
+		return nil, err
+	}
+	if id >= uint64(len(d.refs)) {
+		d.pos = start
+		return nil, d.error("reference to value %d of %d", id, len(d.refs))
+	}
+
+	v := d.refs[id]
+	if objref {
+		switch v.(type) {
+		case *phpserialize.Object, *phpserialize.Custom:
+		default:
+			d.pos = start
+			return nil, d.error("object reference to a %T", v)
+		}
+	}
+	return v, nil
+}
+
+// count reads an element count, checked against the input left: an element takes at least 2 bytes.
+func (d *decoder) count(t, t8 byte) (int, error) {
+	start := d.pos
+	n, err := d.size(width(t, t8))
+	if err != nil {
+		return 0, err
+	}
+	if n > uint64(len(d.data)-d.pos)/2 {
+		d.pos = start
+		return 0, d.error("%d elements, the input is too short for them", n)
+	}
+	return int(n), nil
+}
+
+func (d *decoder) array(t byte) (*phparray.Array, error) {
+	n, err := d.count(t, typeArray8)
+	if err != nil {
+		return nil, err
+	}
+
+	a := phparray.New()
+	d.refs = append(d.refs, a)
+	return a, d.elements(a, n)
+}
+
+// elements reads the n keys and values of an array or of the properties of an object.
+func (d *decoder) elements(a *phparray.Array, n int) error {
+	if d.config.MaxDepth > 0 && d.depth >= d.config.MaxDepth {
+		return d.error("maximum depth of %d exceeded", d.config.MaxDepth)
+	}
+	d.depth++
+	defer func() { d.depth-- }()
+
+	for i := 0; i < n; i++ {
+		k, err := d.key()
+		if err != nil {
+			return err
+		}
+		v, err := d.value(false)
+		if err != nil {
+			return err
+		}
+		a.Set(k, v)
+	}
+	return nil
+}
+
+// key reads an array key, an integer or a string.
+func (d *decoder) key() (phparray.Key, error) {
+	if d.pos >= len(d.data) {
+		return phparray.Key{}, d.error("unexpected end of input")
+	}
+	t := d.data[d.pos]
+	d.pos++
+
+	switch t {
+	case typeLong8p, typeLong8n, typeLong16p, typeLong16n, typeLong32p, typeLong32n, typeLong64p, typeLong64n:
+		n, err := d.long(t)
+		return phparray.IntKey(n), err
+	case typeStringEmpty, typeStringID8, typeStringID16, typeStringID32, typeString8, typeString16, typeString32:
+		s, err := d.string(t)
+		return phparray.StrKey(s), err
+	}
+	d.pos--
+	return phparray.Key{}, d.error("unexpected type %#02x, want an integer or a string key", t)
+}
+
+// object reads a class name, then the properties or the data of a Serializable object.
+func (d *decoder) object(t byte) (any, error) {
+	var class string
+	var err error
+	switch t {
+	case typeObjectID8, typeObjectID16, typeObjectID32:
+		class, err = d.stringID(width(t, typeObjectID8))
+	default:
+		class, err = d.newString(width(t, typeObject8))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := d.config.AllowedClasses == nil || slices.Contains(d.config.AllowedClasses, class)
+	o := phpserialize.NewObject(class)
+	if !allowed {
+		o.Class = phpserialize.IncompleteClass
+		o.Props.SetString(phpserialize.IncompleteClassName, []byte(class))
+	}
+	slot := len(d.refs)
+	d.refs = append(d.refs, o)
+
+	if d.pos >= len(d.data) {
+		return nil, d.error("unexpected end of input")
+	}
+	switch t = d.data[d.pos]; t {
+	case typeArray8, typeArray16, typeArray32:
+		d.pos++
+		n, err := d.count(t, typeArray8)
+		if err != nil {
+			return nil, err
+		}
+		return o, d.elements(o.Props, n)
+	case typeObjectSer8, typeObjectSer16, typeObjectSer32:
+		d.pos++
+		n, err := d.size(width(t, typeObjectSer8))
+		if err != nil {
+			return nil, err
+		}
+		if n > uint64(len(d.data)-d.pos) {
+			return nil, d.error("data of %d bytes, %d left", n, len(d.data)-d.pos)
+		}
+		data, _ := d.read(int(n))
+		if !allowed {
+			// the data can only be read by the class
+			return o, nil
+		}
+		c := &phpserialize.Custom{Class: class, Data: slices.Clone(data)}
+		d.refs[slot] = c
+		return c, nil
+	}
+	return nil, d.error("unexpected type %#02x, want the properties or the data of %s", t, class)
+}
@@ -0,0 +1,114 @@
+package igbinary
+
+import (
+	"encoding/hex"
+	"errors"
+	"github.com/xycczZ/php_pack/phparray"
+	"github.com/xycczZ/php_pack/phpserialize"
+	"testing"
+)
+
+func mustHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestUnmarshalReferences(t *testing.T) {
+	// $a = [[]]; $a[1] = &$a[0]; igbinary_serialize($a);
+	v, err := Unmarshal(mustHex("00000002" + "1402" + "0600" + "1400" + "0601" + "0101"))
+	if err != nil {
+		t.Errorf("unmarshal failed: %v\n", err)
+		return
+	}
+	values := v.(*phparray.Array).Values()
+	if values[0] != values[1] {
+		t.Errorf("reference error, expected: one shared array, actual: %p %p\n", values[0], values[1])
+	}
+
+	// $o = new Foo; igbinary_serialize([$o, $o]);
+	v, err = Unmarshal(mustHex("00000002" + "1402" + "0600" + "1703466f6f" + "1400" + "0601" + "2201"))
+	if err != nil {
+		t.Errorf("unmarshal failed: %v\n", err)
+		return
+	}
+	values = v.(*phparray.Array).Values()
+	if o, ok := values[0].(*phpserialize.Object); !ok || o.Class != "Foo" || values[0] != values[1] {
+		t.Errorf("reference error, expected: one shared object Foo, actual: %#v %#v\n", values[0], values[1])
+	}
+
+	// igbinary 1 payloads are read too
+	if v, err := Unmarshal(mustHex("00000001" + "0605")); err != nil || v != int64(5) {
+		t.Errorf("unmarshal version 1 error, expected: 5, actual: %v %v\n", v, err)
+	}
+}
+
+func TestUnmarshalConfig(t *testing.T) {
+	data := mustHex("00000002" + "1402" + "0600" + "1703466f6f" + "1401" + "110161" + "0601" + "0601" + "1703426172" + "1d01" + "78")
+	v, err := UnmarshalConfig(data, Config{AllowedClasses: []string{"Foo"}})
+	if err != nil {
+		t.Errorf("unmarshal failed: %v\n", err)
+		return
+	}
+	actual, _ := phpserialize.Marshal(v)
+	if string(actual) != `a:2:{i:0;O:3:"Foo":1:{s:1:"a";i:1;}i:1;O:3:"Bar":0:{}}` {
+		t.Errorf("allowed classes error, actual: %q\n", actual)
+	}
+
+	cases := []struct {
+		Hex    string
+		Config Config
+		Err    string
+	}{
+		{"00000002" + "1401" + "0600" + "1400", Config{MaxDepth: 1}, "igbinary: error at offset 10 of 10 bytes: maximum depth of 1 exceeded"},
+		{"00000002" + "1402" + "0600" + "0601" + "0601" + "0601", Config{MaxElements: 2}, "igbinary: error at offset 12 of 14 bytes: more than 2 values"},
+	}
+	for _, c := range cases {
+		_, err := UnmarshalConfig(mustHex(c.Hex), c.Config)
+		if err == nil || err.Error() != c.Err {
+			t.Errorf("unmarshal %s error, expected: %s, actual: %v\n", c.Hex, c.Err, err)
+		}
+	}
+
+	if _, err := UnmarshalConfig(mustHex("00000002"+"1401"+"0600"+"1400"), Config{MaxDepth: -1}); err != nil {
+		t.Errorf("unmarshal without depth limit failed: %v\n", err)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	cases := []struct {
+		Hex    string
+		Offset int
+		Msg    string
+	}{
+		{"", 0, "payload of 0 bytes, a header and a value need at least 5"},
+		{"0000000300", 0, "unsupported version 0x3"},
+		{"000000020000", 5, "extra data after the value"},
+		{"00000002ff", 4, "unknown type 0xff"},
+		{"000000020c00", 5, "unexpected end of input, need 8 bytes, 1 left"},
+		{"0000000221ffffffffffffffff", 5, "negative integer out of range"},
+		{"00000002208000000000000000", 5, "integer out of range"},
+		{"000000020e00", 5, "string 0 of 0"},
+		{"00000002110561", 6, "string of 5 bytes, 1 left"},
+		{"0000000214ff", 5, "255 elements, the input is too short for them"},
+		{"0000000214010c0000", 6, "unexpected type 0x0c, want an integer or a string key"},
+		{"000000020101", 5, "reference to value 1 of 0"},
+		{"00000002" + "1402" + "0600" + "1400" + "0601" + "2200", 13, "object reference to a *phparray.Array"},
+		{"00000002" + "1402" + "0600" + "2525" + "0601", 9, "reference to a reference"},
+		{"00000002" + "252525252525", 5, "reference to a reference"},
+		{"000000021703466f6f", 9, "unexpected end of input"},
+		{"000000021703466f6f06", 9, "unexpected type 0x06, want the properties or the data of Foo"},
+		{"000000021703466f6f1d0578", 11, "data of 5 bytes, 1 left"},
+	}
+
+	for _, c := range cases {
+		data := mustHex(c.Hex)
+		_, err := Unmarshal(data)
+		var se *SyntaxError
+		if !errors.As(err, &se) || se.Offset != c.Offset || se.Len != len(data) || se.Msg != c.Msg {
+			t.Errorf("unmarshal %s error, expected: %d %s, actual: %v\n", c.Hex, c.Offset, c.Msg, err)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package igbinary
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/xycczZ/php_pack/phparray"
+	"github.com/xycczZ/php_pack/phpserialize"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// Marshal returns the igbinary serialization of v, what igbinary_serialize() returns for it.
+//
+// v may hold the values Unmarshal returns, any Go integer, float or string type, maps, which are
+// serialized with their keys sorted, and slices and arrays, which become lists. A *Object or
+// *Custom found again is serialized as a reference to the first one. Enum cases can not be serialized.
+func Marshal(v any) ([]byte, error) {
+	e := encoder{
+		buf:     binary.BigEndian.AppendUint32(nil, Version),
+		strings: make(map[string]int),
+		objects: make(map[any]int),
+	}
+	if err := e.encode(v); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+type encoder struct {
+	buf []byte
+	// strings is the string table, the strings and class names written so far by their index
+	strings map[string]int
+	// n numbers the arrays and objects, objects maps the objects to their number
+	n       int
+	objects map[any]int
+	depth   int
+}
+
+func (e *encoder) encode(v any) error {
+	switch x := v.(type) {
+	case nil:
+		e.buf = append(e.buf, typeNull)
+	case bool:
+		if x {
+			e.buf = append(e.buf, typeTrue)
+		} else {
+			e.buf = append(e.buf, typeFalse)
+		}
+	case int64:
+		e.encodeLong(x)
+	case int:
+		e.encodeLong(int64(x))
+	case float64:
+		e.buf = append(e.buf, typeDouble)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, math.Float64bits(x))
+	case string:
+		e.encodeString(x)
+	case []byte:
+		e.encodeString(string(x))
+	case *phparray.Array:
+		if x == nil {
+			e.buf = append(e.buf, typeNull)
+			return nil
+		}
+		e.n++
+		return e.nested(func() error {
+			return e.encodeElements(x, "")
+		})
+	case *phpserialize.Object:
+		if e.seen(x) {
+			return nil
+		}
+		return e.nested(func() error {
+			return e.encodeObject(x)
+		})
+	case *phpserialize.Custom:
+		if e.seen(x) {
+			return nil
+		}
+		e.encodeClass(x.Class)
+		e.encodeSize(len(x.Data), typeObjectSer8, typeObjectSer16, typeObjectSer32)
+		e.buf = append(e.buf, x.Data...)
+	case phpserialize.Enum, *phpserialize.Enum:
+		return fmt.Errorf("igbinary: can not serialize enum cases")
+	default:
+		return e.encodeReflect(reflect.ValueOf(v))
+	}
+	return nil
+}
+
+// seen writes a reference for an object serialized before.
+func (e *encoder) seen(obj any) bool {
+	if reflect.ValueOf(obj).IsNil() {
+		e.buf = append(e.buf, typeNull)
+		return true
+	}
+	if n, ok := e.objects[obj]; ok {
+		e.encodeSize(n, typeObjRef8, typeObjRef16, typeObjRef32)
+		return true
+	}
+	e.objects[obj] = e.n
+	e.n++
+	return false
+}
+
+func (e *encoder) nested(fn func() error) error {
+	if e.depth >= phpserialize.DefaultMaxDepth {
+		return fmt.Errorf("igbinary: maximum depth of %d exceeded, the value may be cyclic", phpserialize.DefaultMaxDepth)
+	}
+	e.depth++
+	err := fn()
+	e.depth--
+	return err
+}
+
+// encodeSize writes the type for the smallest of 8, 16 and 32 bits that holds n, then n.
+func (e *encoder) encodeSize(n int, t8, t16, t32 byte) {
+	switch {
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, t8, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, t16)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(n))
+	default:
+		e.buf = append(e.buf, t32)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(n))
+	}
+}
+
+// encodeLong writes the sign in the type and the magnitude in as few bytes as it needs.
+func (e *encoder) encodeLong(n int64) {
+	if n == math.MinInt64 {
+		e.buf = append(e.buf, typeLong64n)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, 1<<63)
+		return
+	}
+
+	k, negative := uint64(n), n < 0
+	if negative {
+		k = uint64(-n)
+	}
+	switch {
+	case k <= math.MaxUint8:
+		e.buf = append(e.buf, typeLong8p+b2i(negative), byte(k))
+	case k <= math.MaxUint16:
+		e.buf = append(e.buf, typeLong16p+b2i(negative))
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(k))
+	case k <= math.MaxUint32:
+		e.buf = append(e.buf, typeLong32p+b2i(negative))
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(k))
+	default:
+		e.buf = append(e.buf, typeLong64p+b2i(negative))
+		e.buf = binary.BigEndian.AppendUint64(e.buf, k)
+	}
+}
+
+func b2i(b bool) byte {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// encodeString writes s, or its index when it is in the string table already.
+func (e *encoder) encodeString(s string) {
+	if s == "" {
+		e.buf = append(e.buf, typeStringEmpty)
+		return
+	}
+	if id, ok := e.strings[s]; ok {
+		e.encodeSize(id, typeStringID8, typeStringID16, typeStringID32)
+		return
+	}
+	e.strings[s] = len(e.strings)
+	e.encodeSize(len(s), typeString8, typeString16, typeString32)
+	e.buf = append(e.buf, s...)
+}
+
+// encodeClass writes a class name, which shares the string table with the strings.
+func (e *encoder) encodeClass(class string) {
+	if id, ok := e.strings[class]; ok {
+		e.encodeSize(id, typeObjectID8, typeObjectID16, typeObjectID32)
+		return
+	}
+	e.strings[class] = len(e.strings)
+	e.encodeSize(len(class), typeObject8, typeObject16, typeObject32)
+	e.buf = append(e.buf, class...)
+}
+
+// encodeElements writes the array header and the keys and values of a, leaving out the key skip.
+func (e *encoder) encodeElements(a *phparray.Array, skip string) error {
+	n := a.Len()
+	if _, ok := a.GetString(skip); skip != "" && ok {
+		n--
+	}
+	e.encodeSize(n, typeArray8, typeArray16, typeArray32)
+
+	var err error
+	a.Range(func(k phparray.Key, v any) bool {
+		if !k.IsInt() && skip != "" && k.String() == skip {
+			return true
+		}
+		if k.IsInt() {
+			e.encodeLong(k.Int())
+		} else {
+			e.encodeString(k.String())
+		}
+		err = e.encode(v)
+		return err == nil
+	})
+	return err
+}
+
+func (e *encoder) encodeObject(o *phpserialize.Object) error {
+	class, skip := o.Class, ""
+	// an incomplete object is serialized as the object it was
+	if class == phpserialize.IncompleteClass {
+		name, _ := o.Props.GetString(phpserialize.IncompleteClassName)
+		switch name := name.(type) {
+		case []byte:
+			class, skip = string(name), phpserialize.IncompleteClassName
+		case string:
+			class, skip = name, phpserialize.IncompleteClassName
+		}
+	}
+
+	e.encodeClass(class)
+	return e.encodeElements(o.Props, skip)
+}
+
+// encodeReflect serializes the other integer, float and string types, maps, slices and arrays.
+func (e *encoder) encodeReflect(rv reflect.Value) error {
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.encodeLong(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		// php has no unsigned integers, large values wrap like phpconv.ToInt
+		e.encodeLong(int64(rv.Uint()))
+	case reflect.Float32, reflect.Float64:
+		return e.encode(rv.Float())
+	case reflect.Bool:
+		return e.encode(rv.Bool())
+	case reflect.String:
+		e.encodeString(rv.String())
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+			e.encodeString(string(rv.Bytes()))
+			return nil
+		}
+		if (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Map) && rv.IsNil() {
+			e.buf = append(e.buf, typeNull)
+			return nil
+		}
+		a, err := toArray(rv)
+		if err != nil {
+			return err
+		}
+		return e.encode(a)
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			e.buf = append(e.buf, typeNull)
+			return nil
+		}
+		return e.encode(rv.Elem().Interface())
+	default:
+		return fmt.Errorf("igbinary: can not serialize %s", rv.Type())
+	}
+	return nil
+}
+
+// toArray converts a slice or an array to a list, and a map to an array with integer keys
+// in numeric order before string keys in byte order.
+func toArray(rv reflect.Value) (*phparray.Array, error) {
+	a := phparray.New()
+	if rv.Kind() != reflect.Map {
+		for i := 0; i < rv.Len(); i++ {
+			a.Append(rv.Index(i).Interface())
+		}
+		return a, nil
+	}
+
+	keys := make([]phparray.Key, 0, rv.Len())
+	values := make(map[phparray.Key]any, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		k := iter.Key()
+		var key phparray.Key
+		switch k.Kind() {
+		case reflect.String:
+			key = phparray.StrKey(k.String())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			key = phparray.IntKey(k.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			key = phparray.IntKey(int64(k.Uint()))
+		default:
+			return nil, fmt.Errorf("igbinary: can not serialize map key %s", k.Type())
+		}
+		keys = append(keys, key)
+		values[key] = iter.Value().Interface()
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.IsInt() != b.IsInt() {
+			return a.IsInt()
+		}
+		if a.IsInt() {
+			return a.Int() < b.Int()
+		}
+		return a.String() < b.String()
+	})
+
+	for _, k := range keys {
+		a.Set(k, values[k])
+	}
+	return a, nil
+}
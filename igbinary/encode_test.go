@@ -0,0 +1,80 @@
+package igbinary
+
+import (
+	"encoding/hex"
+	"github.com/xycczZ/php_pack/phparray"
+	"github.com/xycczZ/php_pack/phpserialize"
+	"math"
+	"testing"
+)
+
+func TestMarshal(t *testing.T) {
+	foo := phpserialize.NewObject("Foo")
+	foo.Props.SetString("a", int64(1))
+
+	incomplete := phpserialize.NewObject(phpserialize.IncompleteClass)
+	incomplete.Props.SetString(phpserialize.IncompleteClassName, []byte("Bar"))
+	incomplete.Props.SetString("x", true)
+
+	cases := []struct {
+		Value any
+		// Expected is the hex of the value after the header
+		Expected string
+	}{
+		{nil, "00"},
+		{true, "05"},
+		{false, "04"},
+		{1, "0601"},
+		{int64(-1), "0701"},
+		{256, "080100"},
+		{-70000, "0b00011170"},
+		{int64(1) << 40, "200000010000000000"},
+		{int64(math.MinInt64), "218000000000000000"},
+		{uint64(math.MaxUint64), "0701"},
+		{1.5, "0c3ff8000000000000"},
+		{"", "0d"},
+		{"ab", "11026162"},
+		{[]byte("ab"), "11026162"},
+		{[]string{"a", "a"}, "1402" + "0600" + "110161" + "0601" + "0e00"},
+		{map[string]int{"b": 1, "a": 2}, "1402" + "110161" + "0602" + "110162" + "0601"},
+		{[]any{foo, foo}, "1402" + "0600" + "1703466f6f" + "1401" + "110161" + "0601" + "0601" + "2201"},
+		{incomplete, "1703426172" + "1401" + "110178" + "05"},
+		{&phpserialize.Custom{Class: "Foo", Data: []byte("x")}, "1703466f6f" + "1d01" + "78"},
+		{(*phpserialize.Object)(nil), "00"},
+		{[]any(nil), "00"},
+	}
+
+	for _, c := range cases {
+		actual, err := Marshal(c.Value)
+		if err != nil {
+			t.Errorf("marshal %#v failed: %v\n", c.Value, err)
+			continue
+		}
+		expected := "00000002" + c.Expected
+		if hex.EncodeToString(actual) != expected {
+			t.Errorf("marshal %#v error, expected: %s, actual: %x\n", c.Value, expected, actual)
+		}
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	cyclic := phparray.New()
+	cyclic.Append(cyclic)
+
+	cases := []struct {
+		Value any
+		Err   string
+	}{
+		{cyclic, "igbinary: maximum depth of 4096 exceeded, the value may be cyclic"},
+		{phpserialize.Enum{Class: "Suit", Case: "Hearts"}, "igbinary: can not serialize enum cases"},
+		{complex(1, 2), "igbinary: can not serialize complex128"},
+		{map[float64]int{1: 1}, "igbinary: can not serialize map key float64"},
+	}
+
+	for _, c := range cases {
+		_, err := Marshal(c.Value)
+		if err == nil || err.Error() != c.Err {
+			t.Errorf("marshal %T error, expected: %s, actual: %v\n", c.Value, c.Err, err)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package igbinary
+
+import (
+	"testing"
+)
+
+// FuzzUnmarshal checks that decoding never panics, and that what it accepts encodes
+// to a payload that decodes to the same payload.
+func FuzzUnmarshal(f *testing.F) {
+	f.Add(mustHex("00000002" + "1402" + "0600" + "1703466f6f" + "1401" + "110161" + "0c3fe0000000000000" + "0601" + "2201"))
+	f.Add(mustHex("00000002" + "1401" + "0600" + "25" + "1401" + "0600" + "0101"))
+	f.Add(mustHex("00000002" + "1402" + "0600" + "110161" + "0601" + "0e00"))
+	f.Add(mustHex("00000002" + "1703466f6f" + "1d05" + "68656c6c6f"))
+	f.Add(mustHex("00000002" + "16ffffffff"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		v, err := UnmarshalConfig(data, Config{MaxDepth: 64})
+		if err != nil {
+			return
+		}
+		first, err := Marshal(v)
+		if err != nil {
+			// a php reference cycle
+			return
+		}
+
+		v, err = Unmarshal(first)
+		if err != nil {
+			t.Errorf("unmarshal %x failed: %v\n", first, err)
+			return
+		}
+		second, err := Marshal(v)
+		if err != nil || string(second) != string(first) {
+			t.Errorf("round trip %x error, expected: %x, actual: %x %v\n", data, first, second, err)
+		}
+	})
+}
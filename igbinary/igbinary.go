@@ -0,0 +1,77 @@
+// Package igbinary reads and writes the igbinary format of the igbinary PECL extension,
+// igbinary_serialize() and igbinary_unserialize().
+//
+// Values map to Go like in phpserialize: null is nil, bool is bool, int is int64, float is float64,
+// strings are []byte, arrays are *phparray.Array, objects are *phpserialize.Object and objects
+// of Serializable classes are *phpserialize.Custom.
+//
+// A payload is a 4 byte big-endian format version and one value. Integers take the fewest
+// bytes their magnitude needs, strings and class names are written once and then referred to
+// by their index in the string table, and arrays and objects are numbered so later objects
+// and php references can refer to them.
+package igbinary
+
+import (
+	"fmt"
+	"github.com/xycczZ/php_pack/phpserialize"
+)
+
+// Version is the format version Marshal writes, Unmarshal also reads version 1.
+const Version = 2
+
+// The type bytes of igbinary.c's enum igbinary_type.
+const (
+	typeNull        = 0x00
+	typeRef8        = 0x01
+	typeRef16       = 0x02
+	typeRef32       = 0x03
+	typeFalse       = 0x04
+	typeTrue        = 0x05
+	typeLong8p      = 0x06
+	typeLong8n      = 0x07
+	typeLong16p     = 0x08
+	typeLong16n     = 0x09
+	typeLong32p     = 0x0a
+	typeLong32n     = 0x0b
+	typeDouble      = 0x0c
+	typeStringEmpty = 0x0d
+	typeStringID8   = 0x0e
+	typeStringID16  = 0x0f
+	typeStringID32  = 0x10
+	typeString8     = 0x11
+	typeString16    = 0x12
+	typeString32    = 0x13
+	typeArray8      = 0x14
+	typeArray16     = 0x15
+	typeArray32     = 0x16
+	typeObject8     = 0x17
+	typeObject16    = 0x18
+	typeObject32    = 0x19
+	typeObjectID8   = 0x1a
+	typeObjectID16  = 0x1b
+	typeObjectID32  = 0x1c
+	typeObjectSer8  = 0x1d
+	typeObjectSer16 = 0x1e
+	typeObjectSer32 = 0x1f
+	typeLong64p     = 0x20
+	typeLong64n     = 0x21
+	typeObjRef8     = 0x22
+	typeObjRef16    = 0x23
+	typeObjRef32    = 0x24
+	typeRef         = 0x25
+)
+
+// Config limits what UnmarshalConfig accepts, like for phpserialize.
+type Config = phpserialize.Config
+
+// SyntaxError is input that is not a valid igbinary payload or exceeds a limit of the Config.
+type SyntaxError struct {
+	// Offset is the position in the input the error was found at, Len the length of the input
+	Offset int
+	Len    int
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("igbinary: error at offset %d of %d bytes: %s", e.Offset, e.Len, e.Msg)
+}
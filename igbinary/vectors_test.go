@@ -0,0 +1,96 @@
+package igbinary
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/xycczZ/php_pack/phpserialize"
+	"io/fs"
+	"os"
+	"testing"
+)
+
+type vector struct {
+	PHP        string `json:"php"`
+	Hex        string `json:"hex"`
+	Serialized string `json:"serialized"`
+	DecodeOnly bool   `json:"decodeOnly"`
+	// Igbinary is the version of the extension gen.php ran with
+	Igbinary string `json:"igbinary"`
+}
+
+// TestVectors checks the payloads the igbinary extension wrote with gen.php against the
+// serialize() output of the same values, every vector must name the extension version.
+func TestVectors(t *testing.T) {
+	vectors, err := loadVectors("../testdata/igbinary/vectors.json")
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Skip("no vectors from the igbinary extension yet, see testdata/igbinary/README.md")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range vectors {
+		if v.Igbinary == "" {
+			t.Errorf("%s: no igbinary version, vectors.json must be written by gen.php\n", v.PHP)
+		}
+	}
+	checkVectors(t, vectors)
+}
+
+// TestHandwrittenVectors checks the payloads written by hand from igbinary.c, see testdata/igbinary/README.md.
+func TestHandwrittenVectors(t *testing.T) {
+	vectors, err := loadVectors("../testdata/igbinary/handwritten.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkVectors(t, vectors)
+}
+
+func loadVectors(name string) ([]vector, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var vectors []vector
+	if err := json.Unmarshal(data, &vectors); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return vectors, nil
+}
+
+// checkVectors decodes every payload to the serialize() output and encodes the value back to the payload.
+func checkVectors(t *testing.T, vectors []vector) {
+	for _, v := range vectors {
+		payload, err := hex.DecodeString(v.Hex)
+		if err != nil {
+			t.Errorf("%s: bad hex: %v\n", v.PHP, err)
+			continue
+		}
+		value, err := phpserialize.Unmarshal([]byte(v.Serialized))
+		if err != nil {
+			t.Errorf("%s: unserialize failed: %v\n", v.PHP, err)
+			continue
+		}
+		expected, _ := phpserialize.Marshal(value)
+
+		decoded, err := Unmarshal(payload)
+		if err != nil {
+			t.Errorf("%s: unmarshal failed: %v\n", v.PHP, err)
+			continue
+		}
+		actual, err := phpserialize.Marshal(decoded)
+		if err != nil || string(actual) != string(expected) {
+			t.Errorf("%s: unmarshal error, expected: %q, actual: %q %v\n", v.PHP, expected, actual, err)
+		}
+
+		if v.DecodeOnly {
+			continue
+		}
+		encoded, err := Marshal(value)
+		if err != nil || hex.EncodeToString(encoded) != v.Hex {
+			t.Errorf("%s: marshal error, expected: %s, actual: %x %v\n", v.PHP, v.Hex, encoded, err)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// Package diag holds the errors, warnings and options shared by pack and unpack.
+package diag
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Sentinel errors for each category, match them with errors.Is.
+var (
+	// ErrFormat is a problem with the format string: unknown code, overflowing count...
+	ErrFormat = errors.New("invalid format")
+	// ErrArgument is a missing argument or an argument that can not be converted.
+	ErrArgument = errors.New("invalid argument")
+	// ErrInput is input too short for the format, or an offset outside of it.
+	ErrInput = errors.New("invalid input")
+)
+
+// FormatError is a problem with the format string.
+type FormatError struct {
+	// Code is the format code at fault, 0 when the format as a whole is at fault
+	Code byte
+	// Pos is the byte position of Code in the format string
+	Pos int
+	Msg string
+}
+
+func (e *FormatError) Error() string {
+	if e.Code == 0 {
+		return e.Msg
+	}
+	return fmt.Sprintf("type %c: %s", e.Code, e.Msg)
+}
+
+func (e *FormatError) Is(target error) bool {
+	return target == ErrFormat
+}
+
+// ArgumentError is a missing argument or one that can not be converted for its format code.
+type ArgumentError struct {
+	Code byte
+	// Index is the position of the argument in the argument list
+	Index int
+	// Type is the Go type of the argument, nil when the argument is missing
+	Type reflect.Type
+	Msg  string
+	// Err is the conversion error, if any
+	Err error
+}
+
+func (e *ArgumentError) Error() string {
+	if e.Code == 0 {
+		return e.Msg
+	}
+	if e.Type == nil {
+		return fmt.Sprintf("type %c: %s", e.Code, e.Msg)
+	}
+	return fmt.Sprintf("type %c: argument %d (%s): %s", e.Code, e.Index, e.Type, e.Msg)
+}
+
+func (e *ArgumentError) Is(target error) bool {
+	return target == ErrArgument
+}
+
+func (e *ArgumentError) Unwrap() error {
+	return e.Err
+}
+
+// InputError is input that is too short for the format.
+type InputError struct {
+	// Code is the format code that ran out of input, 0 for a bad offset
+	Code byte
+	// Pos is the position in the input where Code started reading
+	Pos int
+	// Need and Have are the bytes the code needs and the bytes left
+	Need int
+	Have int
+	Msg  string
+}
+
+func (e *InputError) Error() string {
+	if e.Code == 0 {
+		return e.Msg
+	}
+	return fmt.Sprintf("type %c: %s, need %d, have %d", e.Code, e.Msg, e.Need, e.Have)
+}
+
+func (e *InputError) Is(target error) bool {
+	return target == ErrInput
+}
+
+// ConvertError wraps the error converting args[index] for code.
+func ConvertError(code byte, index int, arg any, err error) error {
+	return &ArgumentError{
+		Code:  code,
+		Index: index,
+		Type:  reflect.TypeOf(arg),
+		Msg:   err.Error(),
+		Err:   err,
+	}
+}
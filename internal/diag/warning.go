@@ -0,0 +1,126 @@
+package diag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/xycczZ/php_pack/target"
+	"log"
+	"log/slog"
+)
+
+// ErrWarning matches the error a warning turns into in strict mode.
+var ErrWarning = errors.New("php warning")
+
+// Warning is a warning php's pack() or unpack() would emit, e.g. "type x: '*' ignored".
+type Warning struct {
+	// Code is the format code the warning is about, 0 for none
+	Code byte
+	Msg  string
+}
+
+func (w Warning) String() string {
+	if w.Code == 0 {
+		return w.Msg
+	}
+	return fmt.Sprintf("type %c: %s", w.Code, w.Msg)
+}
+
+// WarningError is returned in strict mode in place of a warning.
+type WarningError struct {
+	Warning Warning
+}
+
+func (e *WarningError) Error() string {
+	return e.Warning.String()
+}
+
+func (e *WarningError) Is(target error) bool {
+	return target == ErrWarning
+}
+
+// Config holds the warning handling shared by pack and unpack.
+// Without OnWarning or Logger, warnings that are not collected are logged with the log package.
+type Config struct {
+	// OnWarning is called for every warning.
+	OnWarning func(Warning)
+	// Logger logs every warning at warn level.
+	Logger *slog.Logger
+	// Strict turns every warning into a *WarningError and stops.
+	Strict bool
+	// Version is the php release to behave like, the zero Version is the latest.
+	// It decides which codes exist and how unpack trims a and A.
+	// Before php 8.0, format and argument errors are reported as warnings as well.
+	Version target.Version
+	// Platform is the machine php runs on, the zero Platform is the Go host.
+	// It decides the byte order of s S i I l L q Q f d, the size of i and I,
+	// and whether the 64-bit codes exist.
+	Platform target.Platform
+}
+
+// Warner reports the warnings of one call according to its config.
+type Warner struct {
+	Config *Config
+	// Collected receives the warnings when not nil
+	Collected *[]Warning
+}
+
+// Warn reports a warning, the returned error is not nil in strict mode.
+func (w *Warner) Warn(code byte, format string, args ...any) error {
+	return w.Report(Warning{Code: code, Msg: fmt.Sprintf(format, args...)})
+}
+
+// Replay reports warnings recorded earlier, stopping at the first error.
+func (w *Warner) Replay(warnings []Warning) error {
+	for _, warning := range warnings {
+		if err := w.Report(warning); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Fail reports err as a warning when the php version emits a warning for it,
+// and returns err.
+func (w *Warner) Fail(err error) error {
+	if w.Config == nil || w.Config.Version.ThrowsValueError() {
+		return err
+	}
+
+	var fe *FormatError
+	var ae *ArgumentError
+	switch {
+	case errors.As(err, &fe):
+		_ = w.Report(Warning{Code: fe.Code, Msg: fe.Msg})
+	case errors.As(err, &ae):
+		_ = w.Report(Warning{Code: ae.Code, Msg: ae.Msg})
+	}
+	return err
+}
+
+// Report reports warning, the returned error is not nil in strict mode.
+func (w *Warner) Report(warning Warning) error {
+	if w.Collected != nil {
+		*w.Collected = append(*w.Collected, warning)
+	}
+
+	config := w.Config
+	if config == nil {
+		config = &Config{}
+	}
+
+	if config.OnWarning != nil {
+		config.OnWarning(warning)
+	}
+	if config.Logger != nil {
+		config.Logger.LogAttrs(context.Background(), slog.LevelWarn, warning.String())
+	}
+	if config.OnWarning == nil && config.Logger == nil && w.Collected == nil && !config.Strict {
+		log.Print(warning.String())
+	}
+
+	if config.Strict {
+		return &WarningError{Warning: warning}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package format
+
+import "math"
+
+// Field is one directive of a pack/unpack format string: a code, its repeat
+// count and, for the unpack syntax, the name used to build the result keys.
+type Field struct {
+	Code byte
+	// Arg is the repeat count (or the length for a, A, Z, h, H).
+	// It is -1 when the count was given as '*', and saturates at math.MaxInt.
+	Arg int
+	// HasArg reports whether a count or '*' followed the code.
+	HasArg bool
+	Name   string
+	// Pos is the byte position of Code in the format string
+	Pos int
+}
+
+// Parse splits format into fields.
+//
+// With named set, the unpack syntax is used: everything after the count up to
+// the next '/' is the field name, e.g. "c2chars/n2int".
+// Otherwise every byte after the count starts a new field, as in pack.
+func Parse(format string, named bool) []Field {
+	formatLen := len(format)
+	fields := make([]Field, 0, formatLen)
+
+	for i := 0; i < formatLen; {
+		field := Field{Code: format[i], Arg: 1, Pos: i}
+		i++
+
+		if i < formatLen {
+			c := format[i]
+			if c == '*' {
+				field.Arg = -1
+				field.HasArg = true
+				i++
+			} else if c >= '0' && c <= '9' {
+				field.Arg = int(c - '0')
+				field.HasArg = true
+				i++
+				for i < formatLen && format[i] >= '0' && format[i] <= '9' {
+					// saturate instead of wrapping around
+					if field.Arg > (math.MaxInt-9)/10 {
+						field.Arg = math.MaxInt
+					} else {
+						field.Arg = field.Arg*10 + int(format[i]-'0')
+					}
+					i++
+				}
+			}
+		}
+
+		if named {
+			namePos := i
+			for i < formatLen && format[i] != '/' {
+				i++
+			}
+			field.Name = format[namePos:i]
+			if len(field.Name) > 200 {
+				field.Name = field.Name[:200]
+			}
+			// skip the '/' separator
+			if i < formatLen {
+				i++
+			}
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields
+}
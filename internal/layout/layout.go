@@ -0,0 +1,112 @@
+// Package layout describes where the fields of a pack or unpack format sit in the binary string.
+package layout
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/xycczZ/php_pack/target"
+	"reflect"
+	"strings"
+)
+
+var (
+	int64Type   = reflect.TypeOf(int64(0))
+	float64Type = reflect.TypeOf(float64(0))
+	bytesType   = reflect.TypeOf([]byte(nil))
+)
+
+// Field is one field of a format and the bytes it covers.
+type Field struct {
+	Code byte
+	// Name is the unpack field name, empty for pack formats
+	Name string
+	// Count is the repeat count, or the length for a, A, Z, h and H; -1 for '*'
+	Count int
+	// Offset is the byte offset the field starts at, -1 when it depends on the arguments or the input
+	Offset int
+	// Size is the number of bytes the field covers, -1 when it depends on the arguments or the input.
+	// x and a forward @ cover their NUL padding, X and a backward @ cover nothing
+	// and start at the position they move to.
+	Size int
+	// Order is the byte order of numbers wider than a byte, nil otherwise
+	Order binary.ByteOrder
+	// Signed is set for signed integers and floats
+	Signed bool
+	// Type is the Go type unpack returns for every value: int64, float64 or []byte, nil for x, X and @
+	Type reflect.Type
+	// Pos is the byte position of Code in the format string
+	Pos int
+}
+
+// String describes the field on one line, e.g. `0-3 N "id" 4 bytes big-endian unsigned int64`.
+func (f Field) String() string {
+	var sb strings.Builder
+	switch {
+	case f.Offset < 0:
+		sb.WriteString("?")
+	case f.Size > 1:
+		fmt.Fprintf(&sb, "%d-%d", f.Offset, f.Offset+f.Size-1)
+	default:
+		fmt.Fprintf(&sb, "%d", f.Offset)
+	}
+
+	sb.WriteByte(' ')
+	sb.WriteByte(f.Code)
+	switch {
+	case f.Count < 0:
+		sb.WriteByte('*')
+	case f.Count != 1 || f.Code == '@':
+		fmt.Fprintf(&sb, "%d", f.Count)
+	}
+	if f.Name != "" {
+		fmt.Fprintf(&sb, " %q", f.Name)
+	}
+
+	switch {
+	case f.Size < 0:
+		sb.WriteString(" ? bytes")
+	case f.Size == 1:
+		sb.WriteString(" 1 byte")
+	default:
+		fmt.Fprintf(&sb, " %d bytes", f.Size)
+	}
+
+	switch f.Order {
+	case binary.BigEndian:
+		sb.WriteString(" big-endian")
+	case binary.LittleEndian:
+		sb.WriteString(" little-endian")
+	}
+	if f.Type == int64Type {
+		if f.Signed {
+			sb.WriteString(" signed")
+		} else {
+			sb.WriteString(" unsigned")
+		}
+	}
+	if f.Type != nil {
+		fmt.Fprintf(&sb, " %s", f.Type)
+	}
+	return sb.String()
+}
+
+// Describe fills the byte order, signedness and result type of a field's code.
+func Describe(f *Field, platform target.Platform) {
+	switch f.Code {
+	case 'a', 'A', 'Z', 'h', 'H':
+		f.Type = bytesType
+	case 'c':
+		f.Type, f.Signed = int64Type, true
+	case 'C':
+		f.Type = int64Type
+	case 's', 'i', 'l', 'q':
+		f.Type, f.Signed = int64Type, true
+		f.Order = platform.OrderOf(f.Code)
+	case 'S', 'n', 'v', 'I', 'L', 'N', 'V', 'Q', 'J', 'P':
+		f.Type = int64Type
+		f.Order = platform.OrderOf(f.Code)
+	case 'f', 'g', 'G', 'd', 'e', 'E':
+		f.Type, f.Signed = float64Type, true
+		f.Order = platform.OrderOf(f.Code)
+	}
+}
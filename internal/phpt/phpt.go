@@ -0,0 +1,138 @@
+// Package phpt loads the pack and unpack expectations of testdata/phpt,
+// transcribed from php-src's ext/standard/tests/strings/pack*.phpt and unpack*.phpt.
+//
+// Every file holds the cases of one .phpt, errors and warnings are php's message texts. Values are typed strings:
+// "i:-1" is an int, "f:1.5" a float, "s:text" a string, "x:6162" a binary string in hex,
+// "b:1" a bool and "n:" null.
+package phpt
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/xycczZ/php_pack/target"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// File is the corpus of one .phpt.
+type File struct {
+	Name   string `json:"-"`
+	Source string `json:"source"`
+	// Platform is the php the .phpt runs on: "i386", or a 64-bit little-endian php when empty
+	Platform string       `json:"platform"`
+	Pack     []PackCase   `json:"pack"`
+	Unpack   []UnpackCase `json:"unpack"`
+}
+
+// PackCase is one pack() call: either the output in hex or the error.
+type PackCase struct {
+	Format   string   `json:"format"`
+	Args     []Value  `json:"args"`
+	Hex      string   `json:"hex"`
+	Error    string   `json:"error"`
+	Warnings []string `json:"warnings"`
+}
+
+// UnpackCase is one unpack() call: either the result in order or the error.
+type UnpackCase struct {
+	Format string `json:"format"`
+	Hex    string `json:"hex"`
+	Offset int    `json:"offset"`
+	// Result holds the key and the typed value of every element
+	Result   [][2]string `json:"result"`
+	Error    string      `json:"error"`
+	Warnings []string    `json:"warnings"`
+}
+
+// Target returns the platform of the file.
+func (f *File) Target() (target.Platform, error) {
+	switch f.Platform {
+	case "":
+		return target.AMD64, nil
+	case "i386":
+		return target.I386, nil
+	}
+	return target.Platform{}, fmt.Errorf("phpt: %s: unknown platform %q", f.Name, f.Platform)
+}
+
+// offsetError is the ValueError unpack throws for an offset outside of the data.
+const offsetError = "unpack(): Argument #3 ($offset) must be contained in argument #2 ($data)"
+
+// Message reports whether msg, an error or warning of this library, is the php message php.
+// The library writes php's "Type c:" as "type c:" and the offset error as "offset n is outside of the input".
+func Message(msg, php string) bool {
+	if php == offsetError {
+		return strings.HasPrefix(msg, "offset ") && strings.HasSuffix(msg, " is outside of the input")
+	}
+	if rest, ok := strings.CutPrefix(php, "Type "); ok {
+		php = "type " + rest
+	}
+	return msg == php
+}
+
+// Value is a typed value, see the package doc.
+type Value string
+
+// Decode returns the Go value: int64, float64, string, []byte, bool or nil.
+func (v Value) Decode() (any, error) {
+	if len(v) < 2 || v[1] != ':' {
+		return nil, fmt.Errorf("phpt: bad value %q", v)
+	}
+
+	s := string(v[2:])
+	switch v[0] {
+	case 'i':
+		return strconv.ParseInt(s, 10, 64)
+	case 'f':
+		return strconv.ParseFloat(s, 64)
+	case 's':
+		return s, nil
+	case 'x':
+		return hex.DecodeString(s)
+	case 'b':
+		return s == "1", nil
+	case 'n':
+		return nil, nil
+	}
+	return nil, fmt.Errorf("phpt: bad value %q", v)
+}
+
+// Load reads every .json file of dir.
+func Load(dir string) ([]File, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]File, 0, len(names))
+	for _, name := range names {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+
+		var f File
+		if err := json.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("phpt: %s: %w", name, err)
+		}
+		f.Name = filepath.Base(name)
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// Arguments decodes the arguments of a pack case.
+func (c *PackCase) Arguments() ([]any, error) {
+	args := make([]any, len(c.Args))
+	for i, v := range c.Args {
+		arg, err := v.Decode()
+		if err != nil {
+			return nil, err
+		}
+		args[i] = arg
+	}
+	return args, nil
+}
@@ -0,0 +1,169 @@
+// Package tags derives pack/unpack formats from `php` struct tags.
+//
+// A tag holds one format directive and an optional key name:
+//
+//	Type  uint16   `php:"n"`
+//	Title string   `php:"a16,name=title"`
+//	Vals  []uint32 `php:"N4"`
+//	_     struct{} `php:"x2"`
+//
+// A name can not contain '/' or start with a digit or '*', which unpack would read as the count.
+// Fields without a tag, or tagged "-", are not part of the format.
+package tags
+
+import (
+	"fmt"
+	"github.com/xycczZ/php_pack/internal/format"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Field is one tagged struct field.
+type Field struct {
+	// Index of the field for reflect.Value.FieldByIndex
+	Index []int
+	// GoName is the name of the struct field
+	GoName string
+	Type   reflect.Type
+	Code   byte
+	// Arg is the count of the directive, -1 for '*'
+	Arg int
+	// Name is the key of the field in the unpack result
+	Name string
+}
+
+// Struct is the format derived from a struct type.
+type Struct struct {
+	Fields []Field
+}
+
+var cache sync.Map // reflect.Type -> *Struct
+
+// Of returns the fields of struct type t, the result is cached per type.
+func Of(t reflect.Type) (*Struct, error) {
+	if s, ok := cache.Load(t); ok {
+		return s.(*Struct), nil
+	}
+
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("php tags: %s is not a struct", t)
+	}
+
+	s := &Struct{}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("php")
+		if !ok || tag == "-" {
+			continue
+		}
+		if !sf.IsExported() && sf.Name != "_" {
+			return nil, fmt.Errorf("php tags: field %s of %s is not exported", sf.Name, t)
+		}
+
+		field, err := parseTag(sf, tag)
+		if err != nil {
+			return nil, fmt.Errorf("php tags: field %s of %s: %w", sf.Name, t, err)
+		}
+		s.Fields = append(s.Fields, field)
+	}
+
+	actual, _ := cache.LoadOrStore(t, s)
+	return actual.(*Struct), nil
+}
+
+func parseTag(sf reflect.StructField, tag string) (Field, error) {
+	spec, opts, _ := strings.Cut(tag, ",")
+	fields := format.Parse(spec, false)
+	if len(fields) != 1 {
+		return Field{}, fmt.Errorf("tag %q must hold exactly one format code", tag)
+	}
+
+	field := Field{
+		Index:  sf.Index,
+		GoName: sf.Name,
+		Type:   sf.Type,
+		Code:   fields[0].Code,
+		Arg:    fields[0].Arg,
+		Name:   sf.Name,
+	}
+
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		key, value, _ := strings.Cut(opt, "=")
+		switch key {
+		case "name":
+			if value == "" || strings.ContainsRune(value, '/') {
+				return Field{}, fmt.Errorf("invalid name %q", value)
+			}
+			// a leading digit or '*' would be read as the count of the unpack format
+			if c := value[0]; c >= '0' && c <= '9' || c == '*' {
+				return Field{}, fmt.Errorf("name %q must not start with a digit or '*'", value)
+			}
+			field.Name = value
+		default:
+			return Field{}, fmt.Errorf("unknown tag option %q", opt)
+		}
+	}
+
+	if IsPosition(field.Code) {
+		if sf.Name != "_" {
+			return Field{}, fmt.Errorf("code %c does not hold a value, use a blank (_) field", field.Code)
+		}
+		field.Name = ""
+		return field, nil
+	}
+
+	if !fields[0].HasArg {
+		// the count defaults to the length of an array field
+		if sf.Type.Kind() == reflect.Array {
+			field.Arg = sf.Type.Len()
+		} else if sf.Type.Kind() == reflect.Slice && !IsString(field.Code) {
+			return Field{}, fmt.Errorf("slice field needs a count in tag %q", tag)
+		}
+	}
+
+	return field, nil
+}
+
+// IsPosition reports whether code only moves the position: x, X and @.
+func IsPosition(code byte) bool {
+	return code == 'x' || code == 'X' || code == '@'
+}
+
+// IsString reports whether code reads or writes a single string: a, A, Z, h and H.
+func IsString(code byte) bool {
+	switch code {
+	case 'a', 'A', 'Z', 'h', 'H':
+		return true
+	}
+	return false
+}
+
+// IsNumeric reports whether code reads or writes numbers, every code but the position and string codes.
+func IsNumeric(code byte) bool {
+	return !IsPosition(code) && !IsString(code)
+}
+
+// IsFloat reports whether code is a floating point code.
+func IsFloat(code byte) bool {
+	switch code {
+	case 'f', 'g', 'G', 'd', 'e', 'E':
+		return true
+	}
+	return false
+}
+
+// Directive returns the format directive of the field, e.g. "N4" or "a*".
+func (f *Field) Directive() string {
+	switch {
+	case f.Arg < 0:
+		return string(f.Code) + "*"
+	case f.Arg == 1 && !IsString(f.Code):
+		return string(f.Code)
+	default:
+		return string(f.Code) + strconv.Itoa(f.Arg)
+	}
+}
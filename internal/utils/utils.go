@@ -1,17 +1,7 @@
 package utils
 
 import (
-	"bytes"
-	"fmt"
-	"reflect"
-	"strconv"
-	"sync"
-	"unsafe"
-)
-
-var (
-	endian bool
-	once   sync.Once
+	"encoding/binary"
 )
 
 type numeric interface {
@@ -34,109 +24,11 @@ func Min[T numeric](a, b T) T {
 	return a
 }
 
-func ConvertToString(s any) (string, error) {
-	if s == nil {
-		return "", nil
-	}
-	rv := reflect.ValueOf(s)
-
-	switch rv.Kind() {
-	case reflect.String:
-		return s.(string), nil
-	case reflect.Bool:
-		if rv.Bool() {
-			return "1", nil
-		}
-		return "", nil
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return fmt.Sprintf("%d", rv.Int()), nil
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return fmt.Sprintf("%d", rv.Uint()), nil
-	case reflect.Float32, reflect.Float64:
-		return fmt.Sprintf("%f", rv.Float()), nil
-	case reflect.Slice:
-		if rv.Type().Elem().Kind() == reflect.Uint8 {
-			return string(rv.Bytes()), nil
-		}
-	case reflect.Struct:
-		var f fmt.Stringer
-		if rv.CanConvert(reflect.TypeOf(f)) {
-			return rv.MethodByName("String").Call(nil)[0].String(), nil
-		}
-	}
-	return "", fmt.Errorf("can not convert %v to string\n", s)
-}
-
-func ConvertToLong(s any) (int64, error) {
-	if s == nil {
-		return 0, nil
-	}
-	rv := reflect.ValueOf(s)
-
-	switch rv.Kind() {
-	case reflect.Bool:
-		if rv.Bool() {
-			return 1, nil
-		}
-		return 0, nil
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return rv.Int(), nil
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return int64(rv.Uint()), nil
-	case reflect.Float32, reflect.Float64:
-		return int64(rv.Float()), nil
-	case reflect.String:
-		return strconv.ParseInt(rv.String(), 10, 64)
-	case reflect.Slice:
-		return If[int64](rv.Len() > 0, 1, 0), nil
-	}
-
-	return 0, fmt.Errorf("can not convert %v to long\n", s)
-}
-
-func ConvertToFloat(s any) (float64, error) {
-	if s == nil {
-		return 0, nil
-	}
-
-	rv := reflect.ValueOf(s)
-
-	switch rv.Kind() {
-	case reflect.Float32, reflect.Float64:
-		return rv.Float(), nil
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return float64(rv.Int()), nil
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return float64(rv.Uint()), nil
-	case reflect.Bool:
-		if rv.Bool() {
-			return 1, nil
-		}
-		return 0, nil
-	case reflect.String:
-		return strconv.ParseFloat(rv.String(), 64)
-	case reflect.Slice:
-		return If(rv.Len() > 0, 1.0, 0.0), nil
-	}
-
-	return 0, fmt.Errorf("can not convert %v to float64\n", s)
-}
-
-func IsLittleEndian() bool {
-	once.Do(func() {
-		var value int32 = 1
-		pointer := unsafe.Pointer(&value)
-		pb := (*byte)(pointer)
-
-		endian = *pb == 1
-	})
-
-	return endian
-}
-
 func MemSet(s []byte, c byte, n int) {
-	tmp := bytes.Repeat([]byte{c}, n)
-	copy(s[:n], tmp)
+	s = s[:n]
+	for i := range s {
+		s[i] = c
+	}
 }
 
 func If[T any](condition bool, trueBranch, falseBranch T) T {
@@ -154,61 +46,35 @@ func PrintULongToBuf(buf []byte, num uint64) int {
 	for num > 0 {
 		start--
 		buf[start] = byte(num%10) + '0'
+		num /= 10
 	}
 
 	return start
 }
 
-func PhpPackReverseInt32(arg uint32) uint32 {
-	return ((arg & 0xFF) << 24) | ((arg & 0xFF00) << 8) | ((arg >> 8) & 0xFF00) | ((arg >> 24) & 0xFF)
-}
-
-func PhpPackReverseInt64(arg uint64) uint64 {
-	slices := unsafe.Slice((*uint32)(unsafe.Pointer(&arg)), 2)
-	slices[0] = PhpPackReverseInt32(slices[0])
-	slices[1] = PhpPackReverseInt32(slices[1])
-
-	return *(*uint64)(unsafe.Pointer(&slices[0]))
-}
-
-func PhpPackReverseInt16(arg uint16) uint16 {
-	return ((arg & 0xFF) << 8) | ((arg >> 8) & 0xFF)
-}
-
-func PhpPackParseFloat(littleEndian bool, src []byte) float32 {
-	var f float32
-	fPtr := unsafe.Slice((*byte)(unsafe.Pointer(&f)), 4)
-	copy(fPtr, src[:4])
-
-	u32 := *(*uint32)(unsafe.Pointer(&fPtr[0]))
-	if !IsLittleEndian() {
-		if littleEndian {
-			u32 = PhpPackReverseInt32(*(*uint32)(unsafe.Pointer(&fPtr[0])))
-		}
-	} else {
-		if littleEndian {
-			u32 = PhpPackReverseInt32(*(*uint32)(unsafe.Pointer(&fPtr[0])))
-		}
+// GetUint reads an unsigned integer of size 1, 2, 4 or 8 bytes.
+func GetUint(order binary.ByteOrder, b []byte, size int) uint64 {
+	switch size {
+	case 1:
+		return uint64(b[0])
+	case 2:
+		return uint64(order.Uint16(b))
+	case 4:
+		return uint64(order.Uint32(b))
 	}
-
-	return *(*float32)(unsafe.Pointer(&u32))
+	return order.Uint64(b)
 }
 
-func PhpPackParseDouble(littleEndian bool, src []byte) float64 {
-	var f float64
-	fPtr := unsafe.Slice((*byte)(unsafe.Pointer(&f)), 8)
-	copy(fPtr, src[:8])
-
-	u64 := *(*uint64)(unsafe.Pointer(&fPtr[0]))
-	if !IsLittleEndian() {
-		if littleEndian {
-			u64 = PhpPackReverseInt64(*(*uint64)(unsafe.Pointer(&fPtr[0])))
-		}
-	} else {
-		if littleEndian {
-			u64 = PhpPackReverseInt64(*(*uint64)(unsafe.Pointer(&fPtr[0])))
-		}
+// PutUint writes the low size bytes of v, size is 1, 2, 4 or 8.
+func PutUint(order binary.ByteOrder, b []byte, size int, v uint64) {
+	switch size {
+	case 1:
+		b[0] = byte(v)
+	case 2:
+		order.PutUint16(b, uint16(v))
+	case 4:
+		order.PutUint32(b, uint32(v))
+	default:
+		order.PutUint64(b, v)
 	}
-
-	return *(*float64)(unsafe.Pointer(&u64))
 }
@@ -0,0 +1,78 @@
+package pack
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAppend(t *testing.T) {
+	cases := []struct {
+		Format string
+		Args   []any
+	}{
+		{"nvc*", []any{0x1234, 0x5678, 65, 66}},
+		{"a5A5Z5h3H*", []any{"ab", "ab", "abcdefg", "123", "abcd"}},
+		{"Cx2C@6CX2C", []any{1, 2, 3, 4}},
+		{"qQJPeEgGfd", []any{-1, 2, 3, 4, 1.5, 2.5, 3.5, 4.5, 5.5, 6.5}},
+		{"X", nil},
+	}
+
+	prefix := []byte("prefix")
+	for _, c := range cases {
+		expected, err := PHPPack(c.Format, c.Args...)
+		if err != nil {
+			t.Errorf("pack %s failed: %v\n", c.Format, err)
+			continue
+		}
+
+		// dst keeps its content, with and without room for the result
+		for _, capacity := range []int{len(prefix), 64} {
+			dst := append(make([]byte, 0, capacity), prefix...)
+			r, err := Append(dst, c.Format, c.Args...)
+			if err != nil {
+				t.Errorf("append %s failed: %v\n", c.Format, err)
+				continue
+			}
+			if !bytes.Equal(r, append(prefix, expected...)) {
+				t.Errorf("append %s error, expected: %v, actual: %v\n", c.Format, expected, r)
+			}
+		}
+	}
+
+	// dirty capacity is zeroed before packing
+	f, err := Compile("a4@6")
+	if err != nil {
+		t.Errorf("compile failed: %v\n", err)
+		return
+	}
+	dst := []byte("xxxxxxxxxx")[:0]
+	r, err := f.Append(dst, "ab")
+	if err != nil || !bytes.Equal(r, []byte("ab\000\000\000\000")) {
+		t.Errorf("append error, actual: %q %v\n", r, err)
+	}
+
+	// on error dst is returned unchanged
+	r, err = Append([]byte("ab"), "N2", 1)
+	if err == nil || string(r) != "ab" {
+		t.Errorf("append should fail and keep dst, actual: %q %v\n", r, err)
+	}
+}
+
+func TestAppendAllocs(t *testing.T) {
+	f, err := Compile("nNvVcCqJPsS")
+	if err != nil {
+		t.Errorf("compile failed: %v\n", err)
+		return
+	}
+
+	args := []any{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}
+	buf := make([]byte, 0, 64)
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := f.Append(buf[:0], args...); err != nil {
+			t.Errorf("append failed: %v\n", err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("append of a numeric format should not allocate, allocs: %v\n", allocs)
+	}
+}
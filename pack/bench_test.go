@@ -0,0 +1,69 @@
+package pack
+
+import "testing"
+
+var benchmarks = []struct {
+	Name   string
+	Format string
+	Args   []any
+}{
+	{"numeric", "nNvVcCsSlLqQJP", []any{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14}},
+	{"float", "gGeEfd", []any{1.5, 2.5, 3.5, 4.5, 5.5, 6.5}},
+	{"string", "a16A16Z16a*", []any{"hello", "world", "php", "pack and unpack"}},
+	{"hex", "H64h64H*", []any{
+		"0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+		"fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210",
+		"00112233445566778899aabbccddeeff",
+	}},
+	{"mixed", "NnCa8H8", []any{int64(1), uint16(2), byte(3), "name", "deadbeef"}},
+}
+
+func BenchmarkPHPPack(b *testing.B) {
+	for _, bm := range benchmarks {
+		b.Run(bm.Name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := PHPPack(bm.Format, bm.Args...); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkPack(b *testing.B) {
+	for _, bm := range benchmarks {
+		b.Run(bm.Name, func(b *testing.B) {
+			f, err := Compile(bm.Format)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := f.Pack(bm.Args...); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkAppend(b *testing.B) {
+	for _, bm := range benchmarks {
+		b.Run(bm.Name, func(b *testing.B) {
+			f, err := Compile(bm.Format)
+			if err != nil {
+				b.Fatal(err)
+			}
+			buf := make([]byte, 0, 256)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := f.Append(buf[:0], bm.Args...); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package pack
+
+import (
+	"bufio"
+	"github.com/xycczZ/php_pack/internal/diag"
+	"io"
+)
+
+// Encoder packs records of one format and writes them to a buffered writer.
+// An Encoder is not safe for concurrent use.
+type Encoder struct {
+	w   *bufio.Writer
+	f   *Format
+	buf []byte
+	n   int64
+}
+
+// NewEncoder compiles format and returns an Encoder writing to w.
+// Call Flush when done to write out the buffered records.
+func NewEncoder(w io.Writer, format string) (*Encoder, error) {
+	f, err := Compile(format)
+	if err != nil {
+		return nil, err
+	}
+	return f.NewEncoder(w), nil
+}
+
+// NewEncoder returns an Encoder writing records of f to w.
+func (f *Format) NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{
+		w: bufio.NewWriter(w),
+		f: f,
+	}
+}
+
+// Encode packs args as one record and writes it.
+// The packing buffer is reused between calls.
+func (e *Encoder) Encode(args ...any) error {
+	w := diag.Warner{Config: &e.f.config}
+	buf, err := e.f.append(e.buf[:0], args, &w)
+	if err != nil {
+		return err
+	}
+	e.buf = buf
+
+	n, err := e.w.Write(e.buf)
+	e.n += int64(n)
+	return err
+}
+
+// Written returns the number of bytes written so far, including bytes still buffered.
+func (e *Encoder) Written() int64 {
+	return e.n
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (e *Encoder) Flush() error {
+	return e.w.Flush()
+}
@@ -0,0 +1,56 @@
+package pack
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncoder(t *testing.T) {
+	var out bytes.Buffer
+	enc, err := NewEncoder(&out, "nA*x")
+	if err != nil {
+		t.Errorf("new encoder failed: %v\n", err)
+		return
+	}
+
+	var expected []byte
+	records := [][]any{{1, "hello"}, {2, "a"}, {3, ""}, {4, "world!"}}
+	for _, args := range records {
+		if err := enc.Encode(args...); err != nil {
+			t.Errorf("encode failed: %v\n", err)
+			return
+		}
+		r, err := PHPPack("nA*x", args...)
+		if err != nil {
+			t.Errorf("pack failed: %v\n", err)
+			return
+		}
+		expected = append(expected, r...)
+	}
+
+	if out.Len() != 0 {
+		t.Errorf("records should be buffered until flush\n")
+	}
+	if err := enc.Flush(); err != nil {
+		t.Errorf("flush failed: %v\n", err)
+		return
+	}
+
+	if !bytes.Equal(out.Bytes(), expected) {
+		t.Errorf("encode error, expected: %v, actual: %v\n", expected, out.Bytes())
+	}
+	if enc.Written() != int64(len(expected)) {
+		t.Errorf("written error, expected: %d, actual: %d\n", len(expected), enc.Written())
+	}
+
+	if err := enc.Encode(); err == nil {
+		t.Errorf("encode should fail with not enough arguments\n")
+	}
+	if enc.Written() != int64(len(expected)) {
+		t.Errorf("a failed record should not be written\n")
+	}
+
+	if _, err := NewEncoder(&out, "y"); err == nil {
+		t.Errorf("new encoder should fail on unknown format code\n")
+	}
+}
@@ -0,0 +1,48 @@
+package pack
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestErrors(t *testing.T) {
+	_, err := PHPPack("N2y", 1, 2)
+	var fe *FormatError
+	if !errors.As(err, &fe) || !errors.Is(err, ErrFormat) {
+		t.Errorf("unknown code should be a format error, actual: %v\n", err)
+		return
+	}
+	if fe.Code != 'y' || fe.Pos != 2 || err.Error() != "type y: unknown format code" {
+		t.Errorf("format error error, actual: %+v\n", fe)
+	}
+
+	_, err = PHPPack("x2147483647x1")
+	if !errors.As(err, &fe) || fe.Code != 'x' || fe.Pos != 11 {
+		t.Errorf("overflow should be a format error, actual: %v\n", err)
+	}
+
+	_, err = PHPPack("nN2", 1, 2)
+	var ae *ArgumentError
+	if !errors.As(err, &ae) || !errors.Is(err, ErrArgument) || errors.Is(err, ErrFormat) {
+		t.Errorf("missing argument should be an argument error, actual: %v\n", err)
+		return
+	}
+	if ae.Code != 'N' || ae.Type != nil || err.Error() != "type N: too few arguments" {
+		t.Errorf("argument error error, actual: %+v\n", ae)
+	}
+
+	_, err = PHPPack("nN", 1, struct{}{})
+	if !errors.As(err, &ae) || !errors.Is(err, ErrArgument) {
+		t.Errorf("unconvertible argument should be an argument error, actual: %v\n", err)
+		return
+	}
+	if ae.Code != 'N' || ae.Index != 1 || ae.Type != reflect.TypeOf(struct{}{}) || ae.Err == nil {
+		t.Errorf("argument error error, actual: %+v\n", ae)
+	}
+
+	_, err = PHPPack("a*", []int{1})
+	if !errors.As(err, &ae) || ae.Index != 0 || ae.Type != reflect.TypeOf([]int{}) {
+		t.Errorf("unconvertible string should be an argument error, actual: %v\n", err)
+	}
+}
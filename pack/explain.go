@@ -0,0 +1,70 @@
+package pack
+
+import (
+	"github.com/xycczZ/php_pack/internal/diag"
+	"github.com/xycczZ/php_pack/internal/layout"
+)
+
+// Field is one field of a format and the bytes it covers in the packed string, see Explain.
+type Field = layout.Field
+
+// Explain returns the fields of format with their offset and size in the packed string,
+// computed by the sizing pass of PHPPack.
+// A '*' count depends on the arguments: the size of that field, the offsets from it on
+// and the padding of a later @ are -1.
+func Explain(format string) ([]Field, error) {
+	f, err := Compile(format)
+	if err != nil {
+		return nil, err
+	}
+	return f.Explain()
+}
+
+// Explain is the package Explain for a compiled format.
+func (f *Format) Explain() ([]Field, error) {
+	fields := make([]Field, len(f.codes))
+	formatArgs := make([]int, len(f.args))
+	dynamic := len(f.codes)
+	for i, arg := range f.args {
+		fields[i] = Field{Code: f.codes[i], Count: arg, Pos: f.pos[i]}
+		layout.Describe(&fields[i], f.platform)
+		if arg < 0 {
+			// sized as empty, marked unknown below
+			dynamic = min(dynamic, i)
+			arg = 0
+		}
+		formatArgs[i] = arg
+	}
+
+	var warnings []Warning
+	if _, _, err := f.sizeOf(formatArgs, &diag.Warner{Collected: &warnings}, fields); err != nil {
+		return nil, err
+	}
+
+	for i := dynamic; i < len(fields); i++ {
+		fields[i].Offset = -1
+		if fields[i].Count < 0 || fields[i].Code == '@' {
+			fields[i].Size = -1
+		}
+	}
+	return fields, nil
+}
+
+// SizeOf returns the length of the binary string format packs,
+// it fails when a '*' count makes the length depend on the arguments.
+func SizeOf(format string) (int, error) {
+	f, err := Compile(format)
+	if err != nil {
+		return 0, err
+	}
+
+	size, ok := f.Size()
+	if !ok {
+		for i, arg := range f.args {
+			if arg < 0 {
+				return 0, &FormatError{Code: f.codes[i], Pos: f.pos[i], Msg: "'*' makes the size depend on the arguments"}
+			}
+		}
+	}
+	return size, nil
+}
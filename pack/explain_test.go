@@ -0,0 +1,90 @@
+package pack
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	cases := []struct {
+		Format   string
+		Expected []string
+	}{
+		{"nvc*", []string{
+			"0-1 n 2 bytes big-endian unsigned int64",
+			"2-3 v 2 bytes little-endian unsigned int64",
+			"? c* ? bytes signed int64",
+		}},
+		{"NA16x2h3", []string{
+			"0-3 N 4 bytes big-endian unsigned int64",
+			"4-19 A16 16 bytes []uint8",
+			"20-21 x2 2 bytes",
+			"22-23 h3 2 bytes []uint8",
+		}},
+		{"C4X2@8E", []string{
+			"0-3 C4 4 bytes unsigned int64",
+			"2 X2 0 bytes",
+			"2-7 @8 6 bytes",
+			"8-15 E 8 bytes big-endian float64",
+		}},
+		{"a*JX9@1", []string{
+			"? a* ? bytes []uint8",
+			"? J 8 bytes big-endian unsigned int64",
+			"? X9 0 bytes",
+			"? @1 ? bytes",
+		}},
+	}
+
+	for _, c := range cases {
+		fields, err := Explain(c.Format)
+		if err != nil {
+			t.Errorf("explain %s failed: %v\n", c.Format, err)
+			continue
+		}
+		if len(fields) != len(c.Expected) {
+			t.Errorf("explain %s error, expected %d fields, actual: %v\n", c.Format, len(c.Expected), fields)
+			continue
+		}
+		for i, field := range fields {
+			if field.String() != c.Expected[i] {
+				t.Errorf("explain %s error, field %d expected: %s, actual: %s\n", c.Format, i, c.Expected[i], field)
+			}
+		}
+	}
+
+	fields, err := Explain("xi")
+	if err != nil || fields[1].Pos != 1 || fields[1].Offset != 1 || fields[1].Size != 4 {
+		t.Errorf("explain xi error: %v %v\n", fields, err)
+	}
+
+	if _, err := Explain("Y"); !errors.Is(err, ErrFormat) {
+		t.Errorf("explain of an unknown code should fail, err: %v\n", err)
+	}
+}
+
+func TestSizeOf(t *testing.T) {
+	cases := []struct {
+		Format string
+		Size   int
+	}{
+		{"", 0},
+		{"nNJ", 14},
+		{"a16Z4h5", 23},
+		{"C4X2", 2},
+		{"x@10X3", 7},
+	}
+
+	for _, c := range cases {
+		size, err := SizeOf(c.Format)
+		if err != nil {
+			t.Errorf("size of %s failed: %v\n", c.Format, err)
+		} else if size != c.Size {
+			t.Errorf("size of %s error, expected: %d, actual: %d\n", c.Format, c.Size, size)
+		}
+	}
+
+	var fe *FormatError
+	if _, err := SizeOf("Na*"); !errors.As(err, &fe) || fe.Code != 'a' || fe.Pos != 1 {
+		t.Errorf("size of Na* should fail at a, err: %v\n", err)
+	}
+}
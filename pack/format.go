@@ -0,0 +1,282 @@
+package pack
+
+import (
+	"fmt"
+	"github.com/xycczZ/php_pack/internal/diag"
+	"github.com/xycczZ/php_pack/internal/format"
+	"github.com/xycczZ/php_pack/phpconv"
+	"github.com/xycczZ/php_pack/target"
+	"math"
+)
+
+// Format is a compiled pack format string.
+// It is immutable once compiled and can be shared between goroutines.
+type Format struct {
+	format string
+	codes  []uint8
+	args   []int
+	// pos is the position of each code in the format string
+	pos []int
+
+	// numArgs is the number of arguments consumed when no numeric code uses '*'
+	numArgs int
+	// dynamic is set when the layout depends on the arguments ('*' counts)
+	dynamic bool
+	// size is the output buffer size and length the length of the result
+	// when X or @ move back, only valid when !dynamic
+	size   int
+	length int
+
+	config   Config
+	platform target.Platform
+	// warnings found while compiling, reported by every Pack call like php does
+	parseWarnings []Warning
+	sizeWarnings  []Warning
+}
+
+// Compile parses format once so that it can be used for many Pack calls.
+func Compile(format string) (*Format, error) {
+	return CompileConfig(format, Config{})
+}
+
+// CompileConfig is Compile with the warning handling of config.
+// In strict mode, a format that warns on every call fails to compile.
+func CompileConfig(format string, config Config) (*Format, error) {
+	f, err := compile(format, config)
+	if err != nil {
+		return nil, (&diag.Warner{Config: &config}).Fail(err)
+	}
+
+	if config.Strict {
+		if len(f.parseWarnings) > 0 {
+			return nil, &WarningError{Warning: f.parseWarnings[0]}
+		}
+		if len(f.sizeWarnings) > 0 {
+			return nil, &WarningError{Warning: f.sizeWarnings[0]}
+		}
+	}
+	return f, nil
+}
+
+func compile(format string, config Config) (*Format, error) {
+	fields := parse(format)
+	f := &Format{
+		config:   config,
+		platform: config.Platform.OrHost(),
+		format:   format,
+		codes:    make([]uint8, len(fields)),
+		args:     make([]int, len(fields)),
+		pos:      make([]int, len(fields)),
+	}
+
+	for i, field := range fields {
+		code := field.Code
+		arg := field.Arg
+
+		if !config.Version.HasCode(code) {
+			return nil, &FormatError{Code: code, Pos: field.Pos, Msg: "unknown format code"}
+		}
+		if !f.platform.HasCode(code) {
+			return nil, &FormatError{Code: code, Pos: field.Pos, Msg: "64-bit format codes are not available for 32-bit versions of PHP"}
+		}
+		if (code == 'i' || code == 'I') && !f.platform.ValidIntSize() {
+			return nil, &FormatError{Code: code, Pos: field.Pos, Msg: fmt.Sprintf("sizeof(int) of %d is not 2, 4 or 8", f.platform.IntSize)}
+		}
+
+		switch code {
+		// Never uses any args
+		case 'x', 'X', '@':
+			if arg < 0 {
+				f.parseWarnings = append(f.parseWarnings, Warning{Code: code, Msg: "'*' ignored"})
+				arg = 1
+			}
+		// Always uses one arg
+		case 'a', 'A', 'Z', 'h', 'H':
+			if arg < 0 {
+				f.dynamic = true
+			}
+			f.numArgs++
+		case 'q', 'Q', 'J', 'P', 'c', 'C',
+			's', 'S', 'i', 'I', 'l', 'L', 'n', 'N',
+			'v', 'V', 'f', 'g', 'G', 'd', 'e', 'E':
+			if arg < 0 {
+				f.dynamic = true
+			} else {
+				f.numArgs += arg
+			}
+		default:
+			return nil, &FormatError{Code: code, Pos: field.Pos, Msg: "unknown format code"}
+		}
+
+		f.codes[i] = code
+		f.args[i] = arg
+		f.pos[i] = field.Pos
+	}
+
+	if !f.dynamic {
+		size, length, err := f.sizeOf(f.args, &diag.Warner{Collected: &f.sizeWarnings}, nil)
+		if err != nil {
+			return nil, err
+		}
+		f.size = size
+		f.length = length
+	}
+
+	return f, nil
+}
+
+// String returns the source format string.
+func (f *Format) String() string {
+	return f.format
+}
+
+// Size returns the number of bytes Pack produces, and false when it depends on the arguments.
+func (f *Format) Size() (int, bool) {
+	return f.length, !f.dynamic
+}
+
+func parse(f string) []format.Field {
+	return format.Parse(f, false)
+}
+
+// resolve checks the arguments against the format and replaces the '*' counts.
+// The compiled counts are returned as is when the format has no '*'.
+func (f *Format) resolve(args []any, w *diag.Warner) ([]int, error) {
+	numArgs := len(args)
+	formatArgs := f.args
+	if f.dynamic {
+		formatArgs = make([]int, len(f.args))
+		copy(formatArgs, f.args)
+	}
+
+	currentArg := 0
+	for i, code := range f.codes {
+		arg := formatArgs[i]
+		switch code {
+		case 'a', 'A', 'Z', 'h', 'H':
+			if currentArg >= numArgs {
+				return nil, &ArgumentError{Code: code, Index: currentArg, Msg: "not enough arguments"}
+			}
+			if arg < 0 {
+				argStr, err := phpconv.ToString(args[currentArg])
+				if err != nil {
+					return nil, diag.ConvertError(code, currentArg, args[currentArg], err)
+				}
+				arg = len(argStr)
+				if code == 'Z' {
+					// add one because Z is always NUL-terminated:
+					// pack("Z*", "aa") == "aa\0"
+					// pack("Z2", "aa") == "a\0"
+					arg++
+				}
+			}
+
+			currentArg++
+		case 'x', 'X', '@':
+		default:
+			if arg < 0 {
+				arg = numArgs - currentArg
+			}
+			if currentArg > math.MaxInt-arg || currentArg+arg > numArgs {
+				return nil, &ArgumentError{Code: code, Index: numArgs, Msg: "too few arguments"}
+			}
+			currentArg += arg
+		}
+
+		formatArgs[i] = arg
+	}
+
+	if currentArg < numArgs {
+		if err := w.Warn(0, "%d arguments unused", numArgs-currentArg); err != nil {
+			return nil, err
+		}
+	}
+
+	return formatArgs, nil
+}
+
+// sizeOf is the sizing pass of php's pack(), it returns the size of the output buffer
+// and the length of the result, which is the final output position.
+// The bytes each code covers are recorded in fields when it is not nil.
+func (f *Format) sizeOf(formatArgs []int, w *diag.Warner, fields []Field) (int, int, error) {
+	outputPos := 0
+	outputSize := 0
+
+	for i, code := range f.codes {
+		arg := formatArgs[i]
+		start := outputPos
+		switch code {
+		case 'h', 'H':
+			// INC_OUTPUTPOS
+			if err := incOutputPos((arg+(arg%2))/2, 1, code, &outputPos); err != nil {
+				return 0, 0, f.overflowError(i, err)
+			}
+		case 'a', 'A', 'Z', 'c', 'C', 'x':
+			if err := incOutputPos(arg, 1, code, &outputPos); err != nil {
+				return 0, 0, f.overflowError(i, err)
+			}
+		case 's', 'S', 'n', 'v':
+			if err := incOutputPos(arg, 2, code, &outputPos); err != nil {
+				return 0, 0, f.overflowError(i, err)
+			}
+		case 'i', 'I':
+			// sizeof(int)
+			if err := incOutputPos(arg, f.platform.IntSize, code, &outputPos); err != nil {
+				return 0, 0, f.overflowError(i, err)
+			}
+		case 'l', 'L', 'N', 'V':
+			if err := incOutputPos(arg, 4, code, &outputPos); err != nil {
+				return 0, 0, f.overflowError(i, err)
+			}
+		case 'q', 'Q', 'J', 'P':
+			if err := incOutputPos(arg, 8, code, &outputPos); err != nil {
+				return 0, 0, f.overflowError(i, err)
+			}
+		case 'f', 'g', 'G':
+			// sizeof float
+			if err := incOutputPos(arg, 4, code, &outputPos); err != nil {
+				return 0, 0, f.overflowError(i, err)
+			}
+		case 'd', 'e', 'E':
+			// sizeof double
+			if err := incOutputPos(arg, 8, code, &outputPos); err != nil {
+				return 0, 0, f.overflowError(i, err)
+			}
+		case 'X':
+			outputPos -= arg
+			if outputPos < 0 {
+				if err := w.Warn(code, "outside of string"); err != nil {
+					return 0, 0, err
+				}
+				outputPos = 0
+			}
+		case '@':
+			// php's int count can not go past INT_MAX
+			if arg > math.MaxInt32 {
+				return 0, 0, f.overflowError(i, &FormatError{Code: code, Msg: "integer overflow in format string"})
+			}
+			outputPos = arg
+		}
+
+		if outputSize < outputPos {
+			outputSize = outputPos
+		}
+		if fields != nil {
+			if outputPos >= start {
+				fields[i].Offset, fields[i].Size = start, outputPos-start
+			} else {
+				fields[i].Offset, fields[i].Size = outputPos, 0
+			}
+		}
+	}
+
+	return outputSize, outputPos, nil
+}
+
+// overflowError adds the position of field i to an incOutputPos error.
+func (f *Format) overflowError(i int, err error) error {
+	if fe, ok := err.(*FormatError); ok {
+		fe.Pos = f.pos[i]
+	}
+	return err
+}
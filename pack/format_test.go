@@ -0,0 +1,92 @@
+package pack
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	cases := []struct {
+		Format string
+		Args   []any
+		Size   int
+		Fixed  bool
+	}{
+		{"L3", []any{1234, 5678, "777777"}, 12, true},
+		{"c2n2", []any{0x1234, 0x5678, 65, 66}, 6, true},
+		{"A9x2@16", []any{"hello"}, 16, true},
+		{"a*", []any{"hello"}, 0, false},
+		{"C*", []any{1, 2, 3}, 0, false},
+	}
+
+	for i := range cases {
+		f, err := Compile(cases[i].Format)
+		if err != nil {
+			t.Errorf("compile failed, format: %s, err: %v\n", cases[i].Format, err)
+			return
+		}
+
+		size, fixed := f.Size()
+		if size != cases[i].Size || fixed != cases[i].Fixed {
+			t.Errorf("size error, format: %s, expected: %d %v, actual: %d %v\n", cases[i].Format, cases[i].Size, cases[i].Fixed, size, fixed)
+			return
+		}
+
+		expected, err := PHPPack(cases[i].Format, cases[i].Args...)
+		if err != nil {
+			t.Errorf("pack failed: %v\n", err)
+			return
+		}
+		actual, err := f.Pack(cases[i].Args...)
+		if err != nil {
+			t.Errorf("compiled pack failed: %v\n", err)
+			return
+		}
+		if !bytes.Equal(expected, actual) {
+			t.Errorf("compiled pack error, format: %s, expected: %v, actual: %v\n", cases[i].Format, expected, actual)
+			return
+		}
+	}
+
+	if _, err := Compile("L2y"); err == nil {
+		t.Errorf("compile should fail on unknown format code\n")
+	}
+
+	f, err := Compile("N2")
+	if err != nil {
+		t.Errorf("compile failed: %v\n", err)
+		return
+	}
+	if _, err := f.Pack(1); err == nil {
+		t.Errorf("pack should fail with too few arguments\n")
+	}
+}
+
+func TestFormatConcurrent(t *testing.T) {
+	f, err := Compile("nA*")
+	if err != nil {
+		t.Errorf("compile failed: %v\n", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				r, err := f.Pack(i, "abc"[:i%4])
+				if err != nil {
+					t.Errorf("pack failed: %v\n", err)
+					return
+				}
+				if len(r) != 2+i%4 || r[1] != byte(i) {
+					t.Errorf("pack error, actual: %v\n", r)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,61 @@
+package pack
+
+import (
+	"github.com/xycczZ/php_pack/internal/diag"
+	"testing"
+)
+
+// maxFuzzSize bounds the output of a fuzzed format, pack allocates it before packing.
+const maxFuzzSize = 1 << 20
+
+// FuzzPack checks that pack never panics, whatever the format and the arguments.
+func FuzzPack(f *testing.F) {
+	f.Add("c2n2", []byte("\x12\x34\x56\x78"))
+	f.Add("a*A5Z*h*H3", []byte("hello"))
+	f.Add("x*X5@3X*", []byte{})
+	f.Add("qQJPeEgGfd", []byte{0xff, 0, 1})
+	f.Add("C*", []byte("abc"))
+	f.Add("h2147483647", []byte("1"))
+	f.Add("X9223372036854775807@9223372036854775807", []byte{})
+
+	f.Fuzz(func(t *testing.T, format string, data []byte) {
+		c, err := CompileConfig(format, Config{OnWarning: func(Warning) {}})
+		if err != nil {
+			return
+		}
+
+		args := fuzzArgs(data)
+		formatArgs, size, err := c.layout(args, &diag.Warner{Config: &c.config})
+		if err != nil || size > maxFuzzSize {
+			return
+		}
+
+		output := make([]byte, size)
+		n, err := c.packInto(output, formatArgs, args, &diag.Warner{Config: &c.config})
+		if err == nil && (n < 0 || n > size) {
+			t.Errorf("pack %q: length %d outside of the output size %d\n", format, n, size)
+		}
+	})
+}
+
+// fuzzArgs turns data into arguments of every kind pack accepts.
+func fuzzArgs(data []byte) []any {
+	var args []any
+	for i, b := range data {
+		switch i % 6 {
+		case 0:
+			args = append(args, int(int8(b)))
+		case 1:
+			args = append(args, string(data[i:]))
+		case 2:
+			args = append(args, float64(b)/3)
+		case 3:
+			args = append(args, b%2 == 0)
+		case 4:
+			args = append(args, data[:i])
+		default:
+			args = append(args, uint64(b)<<56)
+		}
+	}
+	return args
+}
@@ -0,0 +1,198 @@
+package pack
+
+import (
+	"fmt"
+	"github.com/xycczZ/php_pack/internal/tags"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type structFormat struct {
+	fields []tags.Field
+	// format is nil when a field uses '*' and the format depends on the value
+	format *Format
+}
+
+var structFormats sync.Map // reflect.Type -> *structFormat
+
+// Marshal packs the struct v (or a pointer to it) according to its `php` struct tags,
+// the same tags unpack.Into reads.
+//
+// Numeric codes take integer, float or bool fields, a count greater than one or '*'
+// takes a slice or array field with that many elements.
+// a, A, Z, h, H take string, []byte or [N]byte fields.
+// A field that does not fit its code is an *ArgumentError, its Index is the position of the argument like for Pack.
+func Marshal(v any) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("pack marshal: need a struct, got %T", v)
+	}
+
+	sf, err := compileStruct(rv.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	var directives []string
+	if sf.format == nil {
+		directives = make([]string, 0, len(sf.fields))
+	}
+
+	args := make([]any, 0, len(sf.fields))
+	for i := range sf.fields {
+		field := &sf.fields[i]
+		if tags.IsPosition(field.Code) {
+			if directives != nil {
+				directives = append(directives, field.Directive())
+			}
+			continue
+		}
+
+		var count int
+		args, count, err = appendField(args, field, rv.FieldByIndex(field.Index))
+		if err != nil {
+			return nil, fmt.Errorf("pack marshal: field %s: %w", field.GoName, err)
+		}
+
+		if directives != nil {
+			if field.Arg < 0 && !tags.IsString(field.Code) {
+				directives = append(directives, string(field.Code)+strconv.Itoa(count))
+			} else {
+				directives = append(directives, field.Directive())
+			}
+		}
+	}
+
+	f := sf.format
+	if f == nil {
+		f, err = Compile(strings.Join(directives, ""))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return f.Pack(args...)
+}
+
+// StructFormat returns the pack format derived from the `php` tags of the struct type of v.
+func StructFormat(v any) (string, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil {
+		return "", fmt.Errorf("pack: nil type")
+	}
+
+	s, err := tags.Of(t)
+	if err != nil {
+		return "", err
+	}
+
+	directives := make([]string, len(s.Fields))
+	for i := range s.Fields {
+		directives[i] = s.Fields[i].Directive()
+	}
+	return strings.Join(directives, ""), nil
+}
+
+func compileStruct(t reflect.Type) (*structFormat, error) {
+	if sf, ok := structFormats.Load(t); ok {
+		return sf.(*structFormat), nil
+	}
+
+	s, err := tags.Of(t)
+	if err != nil {
+		return nil, err
+	}
+
+	sf := &structFormat{fields: s.Fields}
+
+	static := true
+	directives := make([]string, len(s.Fields))
+	for i := range s.Fields {
+		if s.Fields[i].Arg < 0 && !tags.IsPosition(s.Fields[i].Code) && !tags.IsString(s.Fields[i].Code) {
+			static = false
+		}
+		directives[i] = s.Fields[i].Directive()
+	}
+
+	// compile anyway to report unknown codes early
+	f, err := Compile(strings.Join(directives, ""))
+	if err != nil {
+		return nil, err
+	}
+	if static {
+		sf.format = f
+	}
+
+	actual, _ := structFormats.LoadOrStore(t, sf)
+	return actual.(*structFormat), nil
+}
+
+// appendField appends the pack arguments of one field, count is the number of arguments it took.
+func appendField(args []any, field *tags.Field, fv reflect.Value) ([]any, int, error) {
+	if tags.IsString(field.Code) {
+		switch {
+		case fv.Kind() == reflect.String:
+			return append(args, fv.String()), 1, nil
+		case fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.Uint8:
+			return append(args, fv.Bytes()), 1, nil
+		case fv.Kind() == reflect.Array && fv.Type().Elem().Kind() == reflect.Uint8:
+			b := make([]byte, fv.Len())
+			reflect.Copy(reflect.ValueOf(b), fv)
+			return append(args, b), 1, nil
+		}
+		return nil, 0, &ArgumentError{Code: field.Code, Index: len(args), Type: fv.Type(), Msg: "not a string, []byte or [N]byte"}
+	}
+
+	switch fv.Kind() {
+	case reflect.Slice, reflect.Array:
+		n := fv.Len()
+		if field.Arg >= 0 && n != field.Arg {
+			return nil, 0, &ArgumentError{Code: field.Code, Index: len(args), Type: fv.Type(), Msg: fmt.Sprintf("%d values for count %d", n, field.Arg)}
+		}
+		for i := 0; i < n; i++ {
+			v, err := numericArg(field.Code, len(args), fv.Index(i))
+			if err != nil {
+				return nil, 0, err
+			}
+			args = append(args, v)
+		}
+		return args, n, nil
+	}
+
+	if field.Arg != 1 {
+		return nil, 0, &ArgumentError{Code: field.Code, Index: len(args), Type: fv.Type(), Msg: fmt.Sprintf("count %s needs a slice or array", countString(field.Arg))}
+	}
+	v, err := numericArg(field.Code, len(args), fv)
+	if err != nil {
+		return nil, 0, err
+	}
+	return append(args, v), 1, nil
+}
+
+func numericArg(code byte, index int, fv reflect.Value) (any, error) {
+	switch fv.Kind() {
+	case reflect.Bool:
+		return fv.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return fv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return fv.Uint(), nil
+	case reflect.Float32, reflect.Float64:
+		return fv.Float(), nil
+	}
+	return nil, &ArgumentError{Code: code, Index: index, Type: fv.Type(), Msg: "not a number or bool"}
+}
+
+func countString(arg int) string {
+	if arg < 0 {
+		return "*"
+	}
+	return strconv.Itoa(arg)
+}
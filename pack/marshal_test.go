@@ -0,0 +1,130 @@
+package pack
+
+import (
+	"bytes"
+	"errors"
+	"github.com/xycczZ/php_pack/unpack"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type record struct {
+	Type  uint16   `php:"n"`
+	Flags uint8    `php:"C"`
+	_     struct{} `php:"x"`
+	Title string   `php:"a8,name=title"`
+	Tag   [4]byte  `php:"A"`
+	Vals  []int32  `php:"N2,name=vals"`
+	ID    uint64   `php:"J"`
+	Note  string
+	Rest  []byte `php:"a*"`
+}
+
+func TestMarshal(t *testing.T) {
+	format, err := StructFormat(record{})
+	if err != nil {
+		t.Errorf("struct format failed: %v\n", err)
+		return
+	}
+	if format != "nCxa8A4N2Ja*" {
+		t.Errorf("struct format error, actual: %s\n", format)
+	}
+
+	r := record{
+		Type:  0x0102,
+		Flags: 3,
+		Title: "hello",
+		Tag:   [4]byte{'a', 'b'},
+		Vals:  []int32{10, 20},
+		ID:    1<<64 - 1,
+		Note:  "not packed",
+		Rest:  []byte("tail"),
+	}
+
+	actual, err := Marshal(&r)
+	if err != nil {
+		t.Errorf("marshal failed: %v\n", err)
+		return
+	}
+	expected, err := PHPPack("nCxa8A4N2Ja*", 0x0102, 3, "hello", []byte{'a', 'b', 0, 0}, 10, 20, -1, "tail")
+	if err != nil {
+		t.Errorf("pack failed: %v\n", err)
+		return
+	}
+	if !bytes.Equal(actual, expected) {
+		t.Errorf("marshal error, expected: %v, actual: %v\n", expected, actual)
+		return
+	}
+
+	var back record
+	if err := unpack.Into(actual, &back); err != nil {
+		t.Errorf("unpack into failed: %v\n", err)
+		return
+	}
+	r.Title = "hello\000\000\000"
+	r.Note = ""
+	if !reflect.DeepEqual(back, r) {
+		t.Errorf("round trip error, expected: %+v, actual: %+v\n", r, back)
+	}
+}
+
+func TestMarshalStar(t *testing.T) {
+	v := struct {
+		Vals []uint16 `php:"n*"`
+		Len  uint8    `php:"C"`
+	}{Vals: []uint16{1, 2, 3}, Len: 3}
+
+	actual, err := Marshal(v)
+	if err != nil {
+		t.Errorf("marshal failed: %v\n", err)
+		return
+	}
+	if !bytes.Equal(actual, []byte{0, 1, 0, 2, 0, 3, 3}) {
+		t.Errorf("marshal error, actual: %v\n", actual)
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	cases := []struct {
+		V   any
+		Err string
+	}{
+		{struct {
+			V []uint16 `php:"n3"`
+		}{V: []uint16{1}}, "type n: argument 0 ([]uint16): 1 values for count 3"},
+		{struct {
+			V uint16 `php:"n2"`
+		}{}, "needs a slice or array"},
+		{struct {
+			V string `php:"n"`
+		}{}, "type n: argument 0 (string): not a number or bool"},
+		{struct {
+			V int `php:"a4"`
+		}{}, "type a: argument 0 (int): not a string"},
+		{struct {
+			V int `php:"y"`
+		}{}, "unknown format code"},
+		{struct {
+			V int `php:"x"`
+		}{}, "use a blank (_) field"},
+		{42, "need a struct"},
+	}
+
+	for i := range cases {
+		_, err := Marshal(cases[i].V)
+		if err == nil || !strings.Contains(err.Error(), cases[i].Err) {
+			t.Errorf("case %d: error should contain %q, actual: %v\n", i, cases[i].Err, err)
+		}
+	}
+
+	_, err := Marshal(struct {
+		A uint16   `php:"n"`
+		B []uint16 `php:"n2"`
+		C uint8    `php:"C2"`
+	}{B: []uint16{1, 2}})
+	var ae *ArgumentError
+	if !errors.Is(err, ErrArgument) || !errors.As(err, &ae) || ae.Code != 'C' || ae.Index != 3 || ae.Type != reflect.TypeOf(uint8(0)) {
+		t.Errorf("argument error, expected: type C, index 3, uint8, actual: %#v\n", err)
+	}
+}
@@ -0,0 +1,123 @@
+package pack
+
+import (
+	"errors"
+	"fmt"
+	"github.com/xycczZ/php_pack/internal/format"
+	"github.com/xycczZ/php_pack/internal/tags"
+	"github.com/xycczZ/php_pack/phparray"
+	"strconv"
+	"strings"
+)
+
+// PackNamed packs the values of a map with the named format syntax of unpack, "c2chars/n2int":
+// every field takes the values of the keys unpack would return for it. What unpack returns packs
+// back unless php overwrote a key: unpack("C2/n", ...) keeps the n value under "1" and loses the
+// first C value, so PackNamed packs the n value for both.
+//
+// A field with a count of one or a string code (a, A, Z, h, H) takes the key name, a numeric field
+// with a count n takes name1 to namen, and with '*' name1, name2... as long as they are set.
+// Unnamed fields take the keys 1, 2..., x, X and @ take no value. Keys no field takes are ignored.
+func PackNamed(format string, values map[string]any) ([]byte, error) {
+	return packNamed(format, func(key string) (any, bool) {
+		v, ok := values[key]
+		return v, ok
+	})
+}
+
+// PackNamedArray is PackNamed taking the values of the ordered array PHPUnpackArray returns.
+func PackNamedArray(format string, values *phparray.Array) ([]byte, error) {
+	return packNamed(format, func(key string) (any, bool) {
+		if values == nil {
+			return nil, false
+		}
+		return values.GetString(key)
+	})
+}
+
+func packNamed(namedFormat string, lookup func(key string) (any, bool)) ([]byte, error) {
+	fields := format.Parse(namedFormat, true)
+	directives := make([]string, 0, len(fields))
+	args := make([]any, 0, len(fields))
+
+	for _, field := range fields {
+		code := field.Code
+		directive := string(code)
+		if field.HasArg {
+			if field.Arg < 0 {
+				directive += "*"
+			} else {
+				directive += strconv.Itoa(field.Arg)
+			}
+		}
+
+		switch {
+		case tags.IsPosition(code):
+		case tags.IsString(code), !field.HasArg, field.Arg == 1:
+			v, err := namedValue(lookup, field, keyName(field.Name, ""), len(args))
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, v)
+		case field.Arg < 0:
+			n := 0
+			for ; ; n++ {
+				v, ok := lookup(keyName(field.Name, strconv.Itoa(n+1)))
+				if !ok {
+					break
+				}
+				args = append(args, v)
+			}
+			directive = string(code) + strconv.Itoa(n)
+		default:
+			for i := 1; i <= field.Arg; i++ {
+				v, err := namedValue(lookup, field, keyName(field.Name, strconv.Itoa(i)), len(args))
+				if err != nil {
+					return nil, err
+				}
+				args = append(args, v)
+			}
+		}
+		directives = append(directives, directive)
+	}
+
+	f, err := Compile(strings.Join(directives, ""))
+	if err != nil {
+		// point at the code in the named format
+		var fe *FormatError
+		if errors.As(err, &fe) {
+			fe.Pos = namedPos(fields, directives, fe.Pos)
+		}
+		return nil, err
+	}
+	return f.Pack(args...)
+}
+
+// keyName returns the key unpack gives a value: the name followed by its number, the number alone when unnamed.
+func keyName(name, number string) string {
+	if name == "" && number == "" {
+		return "1"
+	}
+	return name + number
+}
+
+// namedValue looks up the value of key, index is the position of its argument.
+func namedValue(lookup func(key string) (any, bool), field format.Field, key string, index int) (any, error) {
+	v, ok := lookup(key)
+	if !ok {
+		return nil, &ArgumentError{Code: field.Code, Index: index, Msg: fmt.Sprintf("no value for %q", key)}
+	}
+	return v, nil
+}
+
+// namedPos maps a position in the joined directives to the position of its code in the named format.
+func namedPos(fields []format.Field, directives []string, pos int) int {
+	start := 0
+	for i, d := range directives {
+		if pos < start+len(d) {
+			return fields[i].Pos
+		}
+		start += len(d)
+	}
+	return pos
+}
@@ -0,0 +1,100 @@
+package pack
+
+import (
+	"bytes"
+	"errors"
+	"github.com/xycczZ/php_pack/unpack"
+	"testing"
+)
+
+func TestPackNamed(t *testing.T) {
+	cases := []struct {
+		Format   string
+		Values   map[string]any
+		Expected string
+		Args     []any
+	}{
+		{"c2chars/n2int", map[string]any{"chars1": 0x34, "chars2": 0x78, "int1": 65, "int2": 66}, "c2n2", []any{0x34, 0x78, 65, 66}},
+		{"Nlen/a3s/x2/v", map[string]any{"len": 3, "s": "abc", "1": 7}, "Na3x2v", []any{3, "abc", 7}},
+		{"C2/n", map[string]any{"1": 1, "2": 2}, "C2n", []any{1, 2, 1}},
+		{"N*vals/Cc/a*rest", map[string]any{"vals1": 1, "vals2": 2, "c": 3, "rest": "xy"}, "N2Ca*", []any{1, 2, 3, "xy"}},
+		{"N*vals", map[string]any{"vals1": 9, "vals": 8}, "N", []any{9}},
+		{"N*vals", map[string]any{}, "N0", nil},
+		{"H*hex/c1one", map[string]any{"hex": "0a0b", "one": -1}, "H*c", []any{"0a0b", -1}},
+	}
+
+	for _, c := range cases {
+		actual, err := PackNamed(c.Format, c.Values)
+		if err != nil {
+			t.Errorf("pack named %s failed: %v\n", c.Format, err)
+			continue
+		}
+		expected, err := PHPPack(c.Expected, c.Args...)
+		if err != nil {
+			t.Errorf("pack %s failed: %v\n", c.Expected, err)
+			continue
+		}
+		if !bytes.Equal(actual, expected) {
+			t.Errorf("pack named %s error, expected: %x, actual: %x\n", c.Format, expected, actual)
+		}
+	}
+}
+
+func TestPackNamedRoundTrip(t *testing.T) {
+	format := "nlen/C2flags/a4name/x/N*vals"
+	data := []byte("\x00\x04\x01\x02abcd\x00\x00\x00\x00\x07\x00\x00\x00\x08")
+
+	m, err := unpack.PHPUnpack(unpack.NewOption(format, data))
+	if err != nil {
+		t.Errorf("unpack failed: %v\n", err)
+		return
+	}
+	actual, err := PackNamed(format, m)
+	if err != nil || !bytes.Equal(actual, data) {
+		t.Errorf("pack named error, expected: %x, actual: %x %v\n", data, actual, err)
+	}
+
+	a, err := unpack.PHPUnpackArray(unpack.NewOption(format, data))
+	if err != nil {
+		t.Errorf("unpack array failed: %v\n", err)
+		return
+	}
+	actual, err = PackNamedArray(format, a)
+	if err != nil || !bytes.Equal(actual, data) {
+		t.Errorf("pack named array error, expected: %x, actual: %x %v\n", data, actual, err)
+	}
+
+	// php keeps the n value under "1", the first C value is lost
+	m, err = unpack.PHPUnpack(unpack.NewOption("C2/n", []byte{1, 2, 0, 3}))
+	if err != nil {
+		t.Errorf("unpack failed: %v\n", err)
+		return
+	}
+	actual, err = PackNamed("C2/n", m)
+	if err != nil || !bytes.Equal(actual, []byte{3, 2, 0, 3}) {
+		t.Errorf("pack named overwritten key error, expected: 03020003, actual: %x %v\n", actual, err)
+	}
+}
+
+func TestPackNamedErrors(t *testing.T) {
+	_, err := PackNamed("Nid/a4name", map[string]any{"id": 1})
+	var ae *ArgumentError
+	if !errors.As(err, &ae) || ae.Index != 1 || err.Error() != `type a: no value for "name"` {
+		t.Errorf("missing value error, expected: argument 1, actual: %v\n", err)
+	}
+
+	_, err = PackNamed("n2int", map[string]any{"int1": 1})
+	if !errors.As(err, &ae) || ae.Index != 1 || err.Error() != `type n: no value for "int2"` {
+		t.Errorf("missing value error, expected: argument 1, actual: %v\n", err)
+	}
+
+	_, err = PackNamed("Nid/yfoo", map[string]any{"id": 1, "foo": 2})
+	var fe *FormatError
+	if !errors.As(err, &fe) || fe.Code != 'y' || fe.Pos != 4 {
+		t.Errorf("unknown code error, expected: y at 4, actual: %v\n", err)
+	}
+
+	if _, err := PackNamedArray("Nid", nil); err == nil {
+		t.Errorf("nil array error, expected: an error\n")
+	}
+}
@@ -0,0 +1,36 @@
+package pack
+
+import "github.com/xycczZ/php_pack/internal/diag"
+
+// Config is the warning handling and the php version of pack calls, shared with the unpack package:
+// a callback, a slog.Logger, or strict mode which turns every warning into an error.
+type Config = diag.Config
+
+// Option holds the format, the arguments and the warning handling of one PHPPackWithOption call.
+type Option struct {
+	Config
+	Format string
+	Args   []any
+	// Warnings receives the warnings of the call
+	Warnings []Warning
+}
+
+func NewOption(format string, args ...any) *Option {
+	return &Option{
+		Format: format,
+		Args:   args,
+	}
+}
+
+// PHPPackWithOption is PHPPack with warning handling,
+// the warnings of the call are collected in option.Warnings.
+func PHPPackWithOption(option *Option) ([]byte, error) {
+	option.Warnings = option.Warnings[:0]
+
+	w := &diag.Warner{Config: &option.Config, Collected: &option.Warnings}
+	f, err := compile(option.Format, option.Config)
+	if err != nil {
+		return nil, w.Fail(err)
+	}
+	return f.pack(option.Args, w)
+}
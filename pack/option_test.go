@@ -0,0 +1,88 @@
+package pack
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestPHPPackWithOption(t *testing.T) {
+	option := NewOption("x*h3X5C", "1g2", 65, 66)
+	r, err := PHPPackWithOption(option)
+	if err != nil {
+		t.Errorf("pack failed: %v\n", err)
+		return
+	}
+	// the output ends at the final position, after C
+	if !bytes.Equal(r, []byte{65}) {
+		t.Errorf("pack error, actual: %v\n", r)
+	}
+
+	expected := []string{"type x: '*' ignored", "1 arguments unused", "type X: outside of string", "type h: illegal hex digit g"}
+	if len(option.Warnings) != len(expected) {
+		t.Errorf("warnings error, expected: %v, actual: %v\n", expected, option.Warnings)
+		return
+	}
+	for i := range expected {
+		if option.Warnings[i].String() != expected[i] {
+			t.Errorf("warning %d error, expected: %s, actual: %s\n", i, expected[i], option.Warnings[i])
+		}
+	}
+
+	// a second call starts with no warnings
+	option.Format = "C"
+	option.Args = []any{1}
+	if _, err := PHPPackWithOption(option); err != nil || len(option.Warnings) != 0 {
+		t.Errorf("warnings should be reset, actual: %v %v\n", err, option.Warnings)
+	}
+
+	option = NewOption("C", 1, 2)
+	option.Strict = true
+	_, err = PHPPackWithOption(option)
+	var we *WarningError
+	if !errors.As(err, &we) || !errors.Is(err, ErrWarning) || we.Warning.Msg != "1 arguments unused" {
+		t.Errorf("strict mode should fail on warnings, actual: %v\n", err)
+	}
+}
+
+func TestCompileConfig(t *testing.T) {
+	var warnings []Warning
+	f, err := CompileConfig("nX3", Config{OnWarning: func(w Warning) {
+		warnings = append(warnings, w)
+	}})
+	if err != nil {
+		t.Errorf("compile failed: %v\n", err)
+		return
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := f.Pack(1); err != nil {
+			t.Errorf("pack failed: %v\n", err)
+			return
+		}
+	}
+	// php warns on every call
+	if len(warnings) != 2 || warnings[1].Code != 'X' {
+		t.Errorf("warnings error, actual: %v\n", warnings)
+	}
+
+	if _, err := CompileConfig("nX3", Config{Strict: true}); !errors.Is(err, ErrWarning) {
+		t.Errorf("strict compile should fail on a warning of every call, actual: %v\n", err)
+	}
+
+	var out bytes.Buffer
+	f, err = CompileConfig("H*", Config{Logger: slog.New(slog.NewTextHandler(&out, nil))})
+	if err != nil {
+		t.Errorf("compile failed: %v\n", err)
+		return
+	}
+	if _, err := f.Pack("zz"); err != nil {
+		t.Errorf("pack failed: %v\n", err)
+		return
+	}
+	if !strings.Contains(out.String(), "level=WARN") || !strings.Contains(out.String(), "illegal hex digit z") {
+		t.Errorf("logger error, actual: %s\n", out.String())
+	}
+}
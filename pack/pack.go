@@ -1,176 +1,104 @@
 package pack
 
 import (
-	"fmt"
+	"encoding/binary"
+	"github.com/xycczZ/php_pack/internal/diag"
 	"github.com/xycczZ/php_pack/internal/utils"
-	"log"
+	"github.com/xycczZ/php_pack/phpconv"
 	"math"
-	"strconv"
-	"unsafe"
+	"slices"
 )
 
-var byteMap [1]int
-var intMap [4]int
-var machineEndianShortMap [2]int
-var bigEndianShortMap [2]int
-var littleEndianShortMap [2]int
-var machineEndianLongMap [4]int
-var bigEndianLongMap [4]int
-var littleEndianLongMap [4]int
-var machineEndianLongLongMap [8]int
-var bigEndianLongLongMap [8]int
-var littleEndianLongLongMap [8]int
-
+// PHPPack packs args into a binary string according to format, like php's pack().
 func PHPPack(format string, args ...any) ([]byte, error) {
-	formatLen := len(format)
-	formatCount := 0
-	currentArg := 0
-	numArgs := len(args)
-
-	formatCodes := make([]uint8, formatLen)
-	formatArgs := make([]int, formatLen)
+	f, err := Compile(format)
+	if err != nil {
+		return nil, err
+	}
+	return f.Pack(args...)
+}
 
-	outputPos := 0
-	outputSize := 0
+// Pack packs args according to the compiled format.
+func (f *Format) Pack(args ...any) ([]byte, error) {
+	return f.pack(args, &diag.Warner{Config: &f.config})
+}
 
-	for i := 0; i < formatLen; formatCount++ {
-		code := format[i]
-		i++
-		arg := 1
+// Append is PHPPack appending the result to dst.
+// It compiles format on every call, use Format.Append to pack without allocating.
+func Append(dst []byte, format string, args ...any) ([]byte, error) {
+	f, err := Compile(format)
+	if err != nil {
+		return dst, err
+	}
+	return f.Append(dst, args...)
+}
 
-		if i < formatLen {
-			c := format[i]
-			if c == '*' {
-				arg = -1
-				i++
-			} else if c >= '0' && c <= '9' {
-				var err error
-				arg, err = strconv.Atoi(string(format[i]))
-				if err != nil {
-					return nil, err
-				}
-				i++
-				for i < formatLen && format[i] >= '0' && format[i] <= '9' {
-					argNext, err := strconv.Atoi(string(format[i]))
-					if err != nil {
-						return nil, err
-					}
-					arg = arg*10 + argNext
-					i++
-				}
-			}
-		}
+// Append packs args and appends the result to dst, growing dst only when it lacks the capacity.
+// On error dst is returned unchanged.
+func (f *Format) Append(dst []byte, args ...any) ([]byte, error) {
+	w := diag.Warner{Config: &f.config}
+	return f.append(dst, args, &w)
+}
 
-		switch code {
-		// Never uses any args
-		case 'x', 'X', '@':
-			if arg < 0 {
-				log.Printf("type: %c: '*' ignored", code)
-				arg = 1
-			}
-		// Always uses one arg
-		case 'a', 'A', 'Z', 'h', 'H':
-			if currentArg >= numArgs {
-				return nil, fmt.Errorf("type %c: not enough arguments", code)
-			}
-			if arg < 0 {
-				argStr, err := utils.ConvertToString(args[currentArg])
-				if err != nil {
-					return nil, err
-				}
-				arg = len(argStr)
-				if code == 'Z' {
-					// add one because Z is always NUL-terminated:
-					// pack("Z*", "aa") == "aa\0"
-					// pack("Z2", "aa") == "a\0"
-					arg++
-				}
-			}
+func (f *Format) pack(args []any, w *diag.Warner) ([]byte, error) {
+	output, err := f.append(nil, args, w)
+	if err != nil {
+		return nil, err
+	}
+	if output == nil {
+		output = []byte{}
+	}
+	return output, nil
+}
 
-			currentArg++
-		case 'q', 'Q', 'J', 'P', 'c', 'C',
-			's', 'S', 'i', 'I', 'l', 'L', 'n', 'N',
-			'v', 'V', 'f', 'g', 'G', 'd', 'e', 'E':
-			if arg < 0 {
-				arg = numArgs - currentArg
-			}
-			if currentArg > math.MaxInt-arg {
-				return nil, fmt.Errorf("type %c: too few arguments", code)
-			}
-			currentArg += arg
-			if currentArg > numArgs {
-				return nil, fmt.Errorf("type %c: too few arguments", code)
-			}
-		default:
-			return nil, fmt.Errorf("type %c: unknown format code", code)
-		}
+func (f *Format) append(dst []byte, args []any, w *diag.Warner) ([]byte, error) {
+	formatArgs, outputSize, err := f.layout(args, w)
+	if err != nil {
+		return dst, w.Fail(err)
+	}
 
-		formatCodes[formatCount] = code
-		formatArgs[formatCount] = arg
+	start := len(dst)
+	grown := slices.Grow(dst, outputSize)[:start+outputSize]
+	output := grown[start:]
+	clear(output)
+	n, err := f.packInto(output, formatArgs, args, w)
+	if err != nil {
+		return dst, w.Fail(err)
 	}
+	return grown[:start+n], nil
+}
 
-	if currentArg < numArgs {
-		log.Printf("%d arguments unused", numArgs-currentArg)
+// layout resolves the counts for args and returns them with the output size.
+func (f *Format) layout(args []any, w *diag.Warner) ([]int, int, error) {
+	if err := w.Replay(f.parseWarnings); err != nil {
+		return nil, 0, err
 	}
 
-	for i := 0; i < formatCount; i++ {
-		code := formatCodes[i]
-		arg := formatArgs[i]
-		switch code {
-		case 'h', 'H':
-			// INC_OUTPUTPOS
-			if err := incOutputPos((arg+(arg%2))/2, 1, code, &outputPos); err != nil {
-				return nil, err
-			}
-		case 'a', 'A', 'Z', 'c', 'C', 'x':
-			if err := incOutputPos(arg, 1, code, &outputPos); err != nil {
-				return nil, err
-			}
-		case 's', 'S', 'n', 'v':
-			if err := incOutputPos(arg, 2, code, &outputPos); err != nil {
-				return nil, err
-			}
-		case 'i', 'I':
-			// sizeof(int)
-			if err := incOutputPos(arg, 4, code, &outputPos); err != nil {
-				return nil, err
-			}
-		case 'l', 'L', 'N', 'V':
-			if err := incOutputPos(arg, 4, code, &outputPos); err != nil {
-				return nil, err
-			}
-		case 'q', 'Q', 'J', 'P':
-			if err := incOutputPos(arg, 8, code, &outputPos); err != nil {
-				return nil, err
-			}
-		case 'f', 'g', 'G':
-			// sizeof float
-			if err := incOutputPos(arg, 4, code, &outputPos); err != nil {
-				return nil, err
-			}
-		case 'd', 'e', 'E':
-			// sizeof double
-			if err := incOutputPos(arg, 8, code, &outputPos); err != nil {
-				return nil, err
-			}
-		case 'X':
-			outputPos -= arg
-			if outputPos < 0 {
-				log.Printf("type %c: outside of string", code)
-				outputPos = 0
-			}
-		case '@':
-			outputPos = arg
-		}
+	formatArgs, err := f.resolve(args, w)
+	if err != nil {
+		return nil, 0, err
+	}
 
-		if outputSize < outputPos {
-			outputSize = outputPos
+	outputSize := f.size
+	if f.dynamic {
+		outputSize, _, err = f.sizeOf(formatArgs, w, nil)
+		if err != nil {
+			return nil, 0, err
 		}
+	} else if err := w.Replay(f.sizeWarnings); err != nil {
+		return nil, 0, err
 	}
+	return formatArgs, outputSize, nil
+}
 
-	output := make([]byte, outputSize)
-	outputPos = 0
-	currentArg = 0
+// packInto packs args into output, which must be zeroed and exactly as large as the layout requires.
+// It returns the length of the result: like php, the output ends at the final position,
+// so X and @ at the end of the format cut it.
+func (f *Format) packInto(output []byte, formatArgs []int, args []any, w *diag.Warner) (int, error) {
+	formatCodes := f.codes
+	formatCount := len(formatCodes)
+	outputPos := 0
+	currentArg := 0
 
 	// do actual packing
 	for i := 0; i < formatCount; i++ {
@@ -182,27 +110,29 @@ func PHPPack(format string, args ...any) ([]byte, error) {
 			argCp := utils.If(code != 'Z', arg, utils.Max(0, arg-1))
 
 			utils.MemSet(output[outputPos:], utils.If[byte](code == 'a' || code == 'Z', '\000', ' '), arg)
-			argStr, err := utils.ConvertToString(args[currentArg])
+			argStr, err := phpconv.ToString(args[currentArg])
 			if err != nil {
-				return nil, err
+				return 0, diag.ConvertError(code, currentArg, args[currentArg], err)
 			}
 			copyLen := utils.Min(len(argStr), argCp)
-			copy(output[outputPos:(outputPos+copyLen)], []byte(argStr)[:copyLen])
+			copy(output[outputPos:(outputPos+copyLen)], argStr[:copyLen])
 
 			outputPos += arg
 			currentArg++
 		case 'h', 'H':
 			nibbleShift := utils.If(code == 'h', 0, 4)
 			first := 1
-			str, err := utils.ConvertToString(args[currentArg])
+			str, err := phpconv.ToString(args[currentArg])
 			if err != nil {
-				return nil, err
+				return 0, diag.ConvertError(code, currentArg, args[currentArg], err)
 			}
 
-			v := []byte(str)
+			v := str
 			outputPos--
 			if arg > len(str) {
-				log.Printf("type %c: not enough characters in string", code)
+				if err := w.Warn(code, "not enough characters in string"); err != nil {
+					return 0, err
+				}
 				arg = len(str)
 			}
 
@@ -217,7 +147,9 @@ func PHPPack(format string, args ...any) ([]byte, error) {
 				} else if n >= 'a' && n <= 'f' {
 					n -= ('a' - 10)
 				} else {
-					log.Printf("type %c: illegal hex digit %c", code, n)
+					if err := w.Warn(code, "illegal hex digit %c", n); err != nil {
+						return 0, err
+					}
 					n = 0
 				}
 
@@ -235,140 +167,41 @@ func PHPPack(format string, args ...any) ([]byte, error) {
 
 			outputPos++
 			currentArg++
-		case 'c', 'C':
+		case 'c', 'C', 's', 'S', 'n', 'v', 'i', 'I',
+			'l', 'L', 'N', 'V', 'q', 'Q', 'J', 'P':
+			size := f.intSize(code)
+			order := f.platform.OrderOf(code)
 			for arg > 0 {
 				arg--
-				if err := pack(args[currentArg], 1, byteMap[:], output[outputPos:(outputPos+1)]); err != nil {
-					return nil, err
+				if err := pack(args[currentArg], size, order, output[outputPos:(outputPos+size)]); err != nil {
+					return 0, diag.ConvertError(code, currentArg, args[currentArg], err)
 				}
 				currentArg++
-				outputPos++
-			}
-		case 's', 'S', 'n', 'v':
-			eMap := machineEndianShortMap[:]
-			if code == 'n' {
-				eMap = bigEndianShortMap[:]
-			} else if code == 'v' {
-				eMap = littleEndianShortMap[:]
+				outputPos += size
 			}
-
-			for arg > 0 {
-				arg--
-				if err := pack(args[currentArg], 2, eMap, output[outputPos:(outputPos+2)]); err != nil {
-					return nil, err
-				}
-				currentArg++
-				outputPos += 2
-			}
-		case 'i', 'I':
-			for arg > 0 {
-				arg--
-				if err := pack(args[currentArg], 4, intMap[:], output[outputPos:(outputPos+4)]); err != nil {
-					return nil, err
-				}
-				outputPos += 4
-				currentArg++
-			}
-		case 'l', 'L', 'N', 'V':
-			eMap := machineEndianLongMap[:]
-			if code == 'N' {
-				eMap = bigEndianLongMap[:]
-			} else if code == 'V' {
-				eMap = littleEndianLongMap[:]
-			}
-
-			for arg > 0 {
-				arg--
-				if err := pack(args[currentArg], 4, eMap, output[outputPos:(outputPos+4)]); err != nil {
-					return nil, err
-				}
-				currentArg++
-				outputPos += 4
-			}
-		case 'q', 'Q', 'J', 'P':
-			eMap := machineEndianLongLongMap[:]
-			if code == 'J' {
-				eMap = bigEndianLongLongMap[:]
-			} else if code == 'P' {
-				eMap = littleEndianLongLongMap[:]
-			}
-
-			for arg > 0 {
-				arg--
-				if err := pack(args[currentArg], 8, eMap, output[outputPos:(outputPos+8)]); err != nil {
-					return nil, err
-				}
-				currentArg++
-				outputPos += 8
-			}
-		case 'f':
-			for arg > 0 {
-				arg--
-
-				v, err := utils.ConvertToFloat(args[currentArg])
-				if err != nil {
-					return nil, err
-				}
-				vv := float32(v)
-				s := unsafe.Slice((*byte)(unsafe.Pointer(&vv)), 4)
-				copy(output[outputPos:(outputPos+4)], s)
-				outputPos += 4
-				currentArg++
-			}
-		case 'g':
-			for arg > 0 {
-				arg--
-				v, err := utils.ConvertToFloat(args[currentArg])
-				if err != nil {
-					return nil, err
-				}
-				phpPackCopyFloat(true, output[outputPos:(outputPos+4)], float32(v))
-				outputPos += 4
-				currentArg++
-			}
-		case 'G':
+		case 'f', 'g', 'G':
+			order := f.platform.OrderOf(code)
 			for arg > 0 {
 				arg--
-				v, err := utils.ConvertToFloat(args[currentArg])
+				v, err := phpconv.ToFloat(args[currentArg])
 				if err != nil {
-					return nil, err
+					return 0, diag.ConvertError(code, currentArg, args[currentArg], err)
 				}
-				phpPackCopyFloat(false, output[outputPos:(outputPos+4)], float32(v))
+				order.PutUint32(output[outputPos:], math.Float32bits(float32(v)))
 				outputPos += 4
 				currentArg++
 			}
-		case 'd':
-			for arg > 0 {
-				arg--
-				v, err := utils.ConvertToFloat(args[currentArg])
-				if err != nil {
-					return nil, err
-				}
-				copy(output[outputPos:(outputPos+8)], unsafe.Slice((*byte)(unsafe.Pointer(&v)), 8))
-				outputPos += 8
-				currentArg++
-			}
-		case 'e':
+		case 'd', 'e', 'E':
+			order := f.platform.OrderOf(code)
 			for arg > 0 {
 				arg--
-				v, err := utils.ConvertToFloat(args[currentArg])
+				v, err := phpconv.ToFloat(args[currentArg])
 				if err != nil {
-					return nil, err
+					return 0, diag.ConvertError(code, currentArg, args[currentArg], err)
 				}
-				phpPackCopyDouble(true, output[outputPos:(outputPos+8)], v)
-				currentArg++
+				order.PutUint64(output[outputPos:], math.Float64bits(v))
 				outputPos += 8
-			}
-		case 'E':
-			for arg > 0 {
-				arg--
-				v, err := utils.ConvertToFloat(args[currentArg])
-				if err != nil {
-					return nil, err
-				}
-				phpPackCopyDouble(false, output[outputPos:(outputPos+8)], v)
 				currentArg++
-				outputPos += 8
 			}
 		case 'x':
 			utils.MemSet(output[outputPos:], '\000', arg)
@@ -386,7 +219,7 @@ func PHPPack(format string, args ...any) ([]byte, error) {
 		}
 	}
 
-	return output, nil
+	return outputPos, nil
 }
 
 // #define INC_OUTPUTPOS(a,b)
@@ -400,164 +233,36 @@ func PHPPack(format string, args ...any) ([]byte, error) {
 //
 //	outputpos += (a)*(b);
 func incOutputPos(a, b int, code uint8, outputPos *int) error {
-	if a < 0 || ((math.MaxInt-(*outputPos))/b) < a {
-		return fmt.Errorf("type %c: integer overflow in format string", code)
+	if a < 0 || ((math.MaxInt32-(*outputPos))/b) < a {
+		return &FormatError{Code: code, Msg: "integer overflow in format string"}
 	}
 	*outputPos += a * b
 	return nil
 }
 
-// pack.c php_pack(zval *val, size_t size, int *map, char *output)
-func pack(val any, size int, byteMap []int, output []byte) error {
-	lv, err := utils.ConvertToLong(val)
-	if err != nil {
-		return err
-	}
-
-	vp := (*byte)(unsafe.Pointer(&lv))
-	for i := 0; i < size; i++ {
-		output[i] = *(*byte)(unsafe.Add(unsafe.Pointer(vp), byteMap[i]))
+// intSize returns the size of an integer code on the platform.
+func (f *Format) intSize(code byte) int {
+	switch code {
+	case 'c', 'C':
+		return 1
+	case 's', 'S', 'n', 'v':
+		return 2
+	case 'i', 'I':
+		return f.platform.IntSize
+	case 'l', 'L', 'N', 'V':
+		return 4
 	}
-	return nil
+	return 8
 }
 
-func phpPackCopyFloat(littleEndian bool, dst []byte, f float32) {
-	u := *(*uint32)(unsafe.Pointer(&f))
-	fv := f
-	if !utils.IsLittleEndian() {
-		if littleEndian {
-			ut := utils.PhpPackReverseInt32(u)
-			fv = *(*float32)(unsafe.Pointer(&ut))
-		}
-	} else {
-		if !littleEndian {
-			ut := utils.PhpPackReverseInt32(u)
-			fv = *(*float32)(unsafe.Pointer(&ut))
-		}
-	}
-
-	copy(dst, unsafe.Slice((*byte)(unsafe.Pointer(&fv)), 4))
-}
-
-func phpPackCopyDouble(littleEndian bool, dst []byte, f float64) {
-	u := *(*uint64)(unsafe.Pointer(&f))
-	fv := f
-	if !utils.IsLittleEndian() {
-		if littleEndian {
-			ut := utils.PhpPackReverseInt64(u)
-			fv = *(*float64)(unsafe.Pointer(&ut))
-		}
-	} else {
-		if !littleEndian {
-			ut := utils.PhpPackReverseInt64(u)
-			fv = *(*float64)(unsafe.Pointer(&ut))
-		}
+// pack.c php_pack(zval *val, size_t size, int *map, char *output):
+// the low size bytes of the zend_long value of val in the given byte order.
+func pack(val any, size int, order binary.ByteOrder, output []byte) error {
+	lv, err := phpconv.ToInt(val)
+	if err != nil {
+		return err
 	}
 
-	copy(dst, unsafe.Slice((*byte)(unsafe.Pointer(&fv)), 8))
-}
-
-func init() {
-	if utils.IsLittleEndian() {
-		byteMap[0] = 0
-		for i := 0; i < 4; i++ {
-			intMap[i] = i
-		}
-
-		machineEndianShortMap[0] = 0
-		machineEndianShortMap[1] = 1
-		bigEndianShortMap[0] = 1
-		bigEndianShortMap[1] = 0
-		littleEndianShortMap[0] = 0
-		littleEndianShortMap[1] = 1
-
-		machineEndianLongMap[0] = 0
-		machineEndianLongMap[1] = 1
-		machineEndianLongMap[2] = 2
-		machineEndianLongMap[3] = 3
-		bigEndianLongMap[0] = 3
-		bigEndianLongMap[1] = 2
-		bigEndianLongMap[2] = 1
-		bigEndianLongMap[3] = 0
-		littleEndianLongMap[0] = 0
-		littleEndianLongMap[1] = 1
-		littleEndianLongMap[2] = 2
-		littleEndianLongMap[3] = 3
-
-		machineEndianLongLongMap[0] = 0
-		machineEndianLongLongMap[1] = 1
-		machineEndianLongLongMap[2] = 2
-		machineEndianLongLongMap[3] = 3
-		machineEndianLongLongMap[4] = 4
-		machineEndianLongLongMap[5] = 5
-		machineEndianLongLongMap[6] = 6
-		machineEndianLongLongMap[7] = 7
-		bigEndianLongLongMap[0] = 7
-		bigEndianLongLongMap[1] = 6
-		bigEndianLongLongMap[2] = 5
-		bigEndianLongLongMap[3] = 4
-		bigEndianLongLongMap[4] = 3
-		bigEndianLongLongMap[5] = 2
-		bigEndianLongLongMap[6] = 1
-		bigEndianLongLongMap[7] = 0
-		littleEndianLongLongMap[0] = 0
-		littleEndianLongLongMap[1] = 1
-		littleEndianLongLongMap[2] = 2
-		littleEndianLongLongMap[3] = 3
-		littleEndianLongLongMap[4] = 4
-		littleEndianLongLongMap[5] = 5
-		littleEndianLongLongMap[6] = 6
-		littleEndianLongLongMap[7] = 7
-	} else {
-		size := 8
-		byteMap[0] = size - 1
-		for i := 0; i < 4; i++ {
-			intMap[i] = size - (4 - i)
-		}
-
-		machineEndianShortMap[0] = size - 2
-		machineEndianShortMap[1] = size - 1
-		bigEndianShortMap[0] = size - 2
-		bigEndianShortMap[1] = size - 1
-		littleEndianShortMap[0] = size - 1
-		littleEndianShortMap[1] = size - 2
-
-		machineEndianLongMap[0] = size - 4
-		machineEndianLongMap[1] = size - 3
-		machineEndianLongMap[2] = size - 2
-		machineEndianLongMap[3] = size - 1
-		bigEndianLongMap[0] = size - 4
-		bigEndianLongMap[1] = size - 3
-		bigEndianLongMap[2] = size - 2
-		bigEndianLongMap[3] = size - 1
-		littleEndianLongMap[0] = size - 1
-		littleEndianLongMap[1] = size - 2
-		littleEndianLongMap[2] = size - 3
-		littleEndianLongMap[3] = size - 4
-
-		machineEndianLongLongMap[0] = size - 8
-		machineEndianLongLongMap[1] = size - 7
-		machineEndianLongLongMap[2] = size - 6
-		machineEndianLongLongMap[3] = size - 5
-		machineEndianLongLongMap[4] = size - 4
-		machineEndianLongLongMap[5] = size - 3
-		machineEndianLongLongMap[6] = size - 2
-		machineEndianLongLongMap[7] = size - 1
-		bigEndianLongLongMap[0] = size - 8
-		bigEndianLongLongMap[1] = size - 7
-		bigEndianLongLongMap[2] = size - 6
-		bigEndianLongLongMap[3] = size - 5
-		bigEndianLongLongMap[4] = size - 4
-		bigEndianLongLongMap[5] = size - 3
-		bigEndianLongLongMap[6] = size - 2
-		bigEndianLongLongMap[7] = size - 1
-		littleEndianLongLongMap[0] = size - 1
-		littleEndianLongLongMap[1] = size - 2
-		littleEndianLongLongMap[2] = size - 3
-		littleEndianLongLongMap[3] = size - 4
-		littleEndianLongLongMap[4] = size - 5
-		littleEndianLongLongMap[5] = size - 6
-		littleEndianLongLongMap[6] = size - 7
-		littleEndianLongLongMap[7] = size - 8
-	}
+	utils.PutUint(order, output, size, uint64(lv))
+	return nil
 }
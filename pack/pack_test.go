@@ -37,3 +37,83 @@ func TestPHPPack(t *testing.T) {
 		}
 	}
 }
+
+type (
+	namedInt    int
+	namedString string
+	namedFloat  float32
+	namedBytes  []byte
+)
+
+func TestNamedTypes(t *testing.T) {
+	r, err := PHPPack("Ca2ga*", namedInt(65), namedString("bc"), namedFloat(1.5), namedBytes("de"))
+	if err != nil {
+		t.Errorf("pack failed: %v\n", err)
+		return
+	}
+	if string(r) != "Abc\x00\x00\xc0?de" {
+		t.Errorf("pack error, actual: %q\n", r)
+	}
+}
+
+func TestArgumentConversion(t *testing.T) {
+	cases := []struct {
+		Format   string
+		Args     []any
+		Expected string
+	}{
+		{"N5", []any{"12abc", " 42", "1e3", "3.7", "0x1A"}, "\x00\x00\x00\x0c\x00\x00\x00\x2a\x00\x00\x03\xe8\x00\x00\x00\x03\x00\x00\x00\x00"},
+		{"J2", []any{"1e100", 1e19}, "\x7f\xff\xff\xff\xff\xff\xff\xff\x8a\xc7\x23\x04\x89\xe8\x00\x00"},
+		{"C3", []any{true, nil, -1.9}, "\x01\x00\xff"},
+		{"E", []any{"-1.5e0x"}, "\xbf\xf8\x00\x00\x00\x00\x00\x00"},
+		{"a*", []any{1.5}, "1.5"},
+		{"a*", []any{0.1 + float64(len("ab"))}, "2.1"},
+		{"A*", []any{1e20}, "1.0E+20"},
+		{"a*a*", []any{true, false}, "1"},
+	}
+
+	for _, c := range cases {
+		r, err := PHPPack(c.Format, c.Args...)
+		if err != nil {
+			t.Errorf("pack failed: %v\n", err)
+			continue
+		}
+		if string(r) != c.Expected {
+			t.Errorf("pack %s error, args: %v, expected: %q, actual: %q\n", c.Format, c.Args, c.Expected, r)
+		}
+	}
+}
+
+// TestPackPositions covers x, X, @, '*' and odd h/H lengths as pack.c implements them.
+func TestPackPositions(t *testing.T) {
+	cases := []struct {
+		Format   string
+		Args     []any
+		Expected string
+	}{
+		{"h3", []any{"123"}, "\x21\x03"},
+		{"H3", []any{"123"}, "\x12\x30"},
+		{"h*", []any{"12345"}, "\x21\x43\x05"},
+		{"H*", []any{"12345"}, "\x12\x34\x50"},
+		{"Cx2C", []any{1, 2}, "\x01\x00\x00\x02"},
+		{"CCX2C", []any{1, 2, 3}, "\x03"},
+		{"C@4C", []any{1, 2}, "\x01\x00\x00\x00\x02"},
+		{"CCC@1", []any{1, 2, 3}, "\x01"},
+		{"CX@2C", []any{1, 2}, "\x00\x00\x02"},
+		{"a3X2a", []any{"abc", "d"}, "\x61\x64"},
+		{"c*", []any{1, 2, 3}, "\x01\x02\x03"},
+		{"Cx2C@6CX2C", []any{1, 2, 3, 4}, "\x01\x00\x00\x02\x00\x04"},
+		{"a4X4", []any{"abcd"}, ""},
+	}
+
+	for _, c := range cases {
+		r, err := PHPPack(c.Format, c.Args...)
+		if err != nil {
+			t.Errorf("pack %s failed: %v\n", c.Format, err)
+			continue
+		}
+		if string(r) != c.Expected {
+			t.Errorf("pack %s error, expected: %q, actual: %q\n", c.Format, c.Expected, r)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package pack
+
+import (
+	"encoding/hex"
+	"github.com/xycczZ/php_pack/target"
+	"testing"
+)
+
+// parityCases are the bytes pack produced on little-endian hosts before the
+// byte order was made explicit, the output must stay the same on every GOARCH.
+var parityCases = []struct {
+	Format string
+	Args   []any
+	Hex    string
+}{
+	{"c4", []any{-1, 127, -128, 255}, "ff7f80ff"},
+	{"C3", []any{0, 256, -2}, "0000fe"},
+	{"s3", []any{-1, 0x1234, 65536}, "ffff34120000"},
+	{"S2", []any{-32768, 0xffff}, "0080ffff"},
+	{"n3", []any{-1, 0x1234, 0x12345}, "ffff12342345"},
+	{"v3", []any{-1, 0x1234, 0x12345}, "ffff34124523"},
+	{"i3", []any{-1, 0x12345678, -2147483648}, "ffffffff7856341200000080"},
+	{"I2", []any{int64(0xffffffff), 1}, "ffffffff01000000"},
+	{"l2", []any{-2, 0x7fffffff}, "feffffffffffff7f"},
+	{"L2", []any{int64(0xfffffffe), 1}, "feffffff01000000"},
+	{"N3", []any{-1, 0x12345678, int64(0x123456789)}, "ffffffff1234567823456789"},
+	{"V3", []any{-1, 0x12345678, int64(0x123456789)}, "ffffffff7856341289674523"},
+	{"q2", []any{-2, int64(0x0102030405060708)}, "feffffffffffffff0807060504030201"},
+	{"Q2", []any{-1, int64(0x7fffffffffffffff)}, "ffffffffffffffffffffffffffffff7f"},
+	{"J2", []any{-2, int64(0x0102030405060708)}, "fffffffffffffffe0102030405060708"},
+	{"P2", []any{-2, int64(0x0102030405060708)}, "feffffffffffffff0807060504030201"},
+	{"f3", []any{1.5, -0.1, 3.4e38}, "0000c03fcdccccbd9ec97f7f"},
+	{"g2", []any{1.5, -0.1}, "0000c03fcdccccbd"},
+	{"G2", []any{1.5, -0.1}, "3fc00000bdcccccd"},
+	{"d3", []any{1.5, -0.1, 1e308}, "000000000000f83f9a9999999999b9bfa0c8eb85f3cce17f"},
+	{"e2", []any{1.5, -0.1}, "000000000000f83f9a9999999999b9bf"},
+	{"E2", []any{1.5, -0.1}, "3ff8000000000000bfb999999999999a"},
+	{"a5A5Z5", []any{"ab", "ab", "abcdefg"}, "616200000061622020206162636400"},
+	{"h5H5", []any{"12345", "abcde"}, "214305abcde0"},
+	{"Cx2C@6CX2C", []any{1, 2, 3, 4}, "010000020004"},
+	{"nvc*", []any{0x1234, 0x5678, 65, 66}, "123478564142"},
+	{"s*", []any{true, false, "12", 3.7}, "010000000c000300"},
+}
+
+func TestParity(t *testing.T) {
+	for _, c := range parityCases {
+		f, err := CompileConfig(c.Format, Config{Platform: target.AMD64})
+		if err != nil {
+			t.Errorf("compile %s failed: %v\n", c.Format, err)
+			continue
+		}
+		r, err := f.Pack(c.Args...)
+		if err != nil {
+			t.Errorf("pack %s failed: %v\n", c.Format, err)
+			continue
+		}
+		if hex.EncodeToString(r) != c.Hex {
+			t.Errorf("pack %s error, expected: %s, actual: %x\n", c.Format, c.Hex, r)
+		}
+	}
+}
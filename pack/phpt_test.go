@@ -0,0 +1,62 @@
+package pack
+
+import (
+	"encoding/hex"
+	"github.com/xycczZ/php_pack/internal/phpt"
+	"testing"
+)
+
+// TestPHPT runs the pack expectations of php-src's .phpt tests, see testdata/phpt.
+func TestPHPT(t *testing.T) {
+	files, err := phpt.Load("../testdata/phpt")
+	if err != nil {
+		t.Fatalf("load phpt failed: %v\n", err)
+	}
+
+	for _, file := range files {
+		platform, err := file.Target()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range file.Pack {
+			args, err := c.Arguments()
+			if err != nil {
+				t.Errorf("%s: %s: %v\n", file.Name, c.Format, err)
+				continue
+			}
+
+			option := NewOption(c.Format, args...)
+			option.Platform = platform
+			r, err := PHPPackWithOption(option)
+			if c.Error != "" {
+				if err == nil || !phpt.Message(err.Error(), c.Error) {
+					t.Errorf("%s: pack %s error, expected: %s, actual: %v\n", file.Name, c.Format, c.Error, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("%s: pack %s failed: %v\n", file.Name, c.Format, err)
+				continue
+			}
+
+			if hex.EncodeToString(r) != c.Hex {
+				t.Errorf("%s: pack %s %v error, expected: %s, actual: %x\n", file.Name, c.Format, c.Args, c.Hex, r)
+			}
+			if !warningsEq(option.Warnings, c.Warnings) {
+				t.Errorf("%s: pack %s warnings error, expected: %v, actual: %v\n", file.Name, c.Format, c.Warnings, option.Warnings)
+			}
+		}
+	}
+}
+
+func warningsEq(actual []Warning, expected []string) bool {
+	if len(actual) != len(expected) {
+		return false
+	}
+	for i := range actual {
+		if !phpt.Message(actual[i].String(), expected[i]) {
+			return false
+		}
+	}
+	return true
+}
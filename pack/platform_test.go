@@ -0,0 +1,99 @@
+package pack
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/xycczZ/php_pack/target"
+	"testing"
+)
+
+func TestPlatforms(t *testing.T) {
+	int16BE := target.Platform{BigEndian: true, IntSize: 2, Is64Bit: true}
+	int64LE := target.Platform{BigEndian: false, IntSize: 8, Is64Bit: true}
+
+	cases := []struct {
+		Platform target.Platform
+		Format   string
+		Args     []any
+		Expected []byte
+	}{
+		{target.AMD64, "sSiIlL", []any{0x0102, 0x0304, 0x05060708, -2, 0x090a0b0c, 0x0d0e0f10},
+			[]byte{2, 1, 4, 3, 8, 7, 6, 5, 0xfe, 0xff, 0xff, 0xff, 0x0c, 0x0b, 0x0a, 0x09, 0x10, 0x0f, 0x0e, 0x0d}},
+		{target.S390X, "sSiIlL", []any{0x0102, 0x0304, 0x05060708, -2, 0x090a0b0c, 0x0d0e0f10},
+			[]byte{1, 2, 3, 4, 5, 6, 7, 8, 0xff, 0xff, 0xff, 0xfe, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10}},
+		{target.AMD64, "qQ", []any{int64(0x0102030405060708), -1},
+			[]byte{8, 7, 6, 5, 4, 3, 2, 1, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{target.PPC64, "q", []any{int64(0x0102030405060708)}, []byte{1, 2, 3, 4, 5, 6, 7, 8}},
+		{target.AMD64, "fd", []any{1.5, -2.5}, []byte{0, 0, 0xc0, 0x3f, 0, 0, 0, 0, 0, 0, 0x04, 0xc0}},
+		{target.S390X, "fd", []any{1.5, -2.5}, []byte{0x3f, 0xc0, 0, 0, 0xc0, 0x04, 0, 0, 0, 0, 0, 0}},
+		// fixed byte order codes do not depend on the platform
+		{target.S390X, "nvNVJPgGeE", []any{1, 1, 1, 1, 1, 1, 1.5, 1.5, 1.5, 1.5},
+			[]byte{0, 1, 1, 0, 0, 0, 0, 1, 1, 0, 0, 0,
+				0, 0, 0, 0, 0, 0, 0, 1, 1, 0, 0, 0, 0, 0, 0, 0,
+				0, 0, 0xc0, 0x3f, 0x3f, 0xc0, 0, 0,
+				0, 0, 0, 0, 0, 0, 0xf8, 0x3f, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+		{target.I386, "iL", []any{-1, int64(0xfffffffe)}, []byte{0xff, 0xff, 0xff, 0xff, 0xfe, 0xff, 0xff, 0xff}},
+		{int16BE, "i2", []any{0x0102, -2}, []byte{1, 2, 0xff, 0xfe}},
+		{int64LE, "I", []any{int64(0x0102030405060708)}, []byte{8, 7, 6, 5, 4, 3, 2, 1}},
+	}
+
+	for _, c := range cases {
+		f, err := CompileConfig(c.Format, Config{Platform: c.Platform})
+		if err != nil {
+			t.Errorf("compile %s on %s failed: %v\n", c.Format, c.Platform, err)
+			continue
+		}
+		if size, ok := f.Size(); !ok || size != len(c.Expected) {
+			t.Errorf("size of %s on %s error, expected: %d, actual: %d\n", c.Format, c.Platform, len(c.Expected), size)
+		}
+
+		r, err := f.Pack(c.Args...)
+		if err != nil {
+			t.Errorf("pack %s on %s failed: %v\n", c.Format, c.Platform, err)
+			continue
+		}
+		if !bytes.Equal(r, c.Expected) {
+			t.Errorf("pack %s on %s error, expected: %v, actual: %v\n", c.Format, c.Platform, c.Expected, r)
+		}
+	}
+}
+
+func TestPlatform32Bit(t *testing.T) {
+	for _, format := range []string{"q", "Q", "J", "P", "NJ"} {
+		option := NewOption(format, 1, 1)
+		option.Platform = target.I386
+		_, err := PHPPackWithOption(option)
+
+		var fe *FormatError
+		if !errors.As(err, &fe) || fe.Msg != "64-bit format codes are not available for 32-bit versions of PHP" {
+			t.Errorf("pack %s on 32-bit php should fail, err: %v\n", format, err)
+		}
+	}
+}
+
+func TestPlatformIntSize(t *testing.T) {
+	cases := []struct {
+		Platform target.Platform
+		Format   string
+	}{
+		{target.Platform{BigEndian: true}, "i"},
+		{target.Platform{BigEndian: true}, "NI*"},
+		{target.Platform{IntSize: 3, Is64Bit: true}, "i2"},
+	}
+
+	for _, c := range cases {
+		option := NewOption(c.Format, 1, 2)
+		option.Platform = c.Platform
+		_, err := PHPPackWithOption(option)
+		var fe *FormatError
+		if !errors.As(err, &fe) || fe.Msg != fmt.Sprintf("sizeof(int) of %d is not 2, 4 or 8", c.Platform.IntSize) {
+			t.Errorf("pack %s on %#v should fail, err: %v\n", c.Format, c.Platform, err)
+		}
+	}
+
+	// formats without i and I do not need sizeof(int)
+	if _, err := CompileConfig("nN", Config{Platform: target.Platform{BigEndian: true}}); err != nil {
+		t.Errorf("compile without i failed: %v\n", err)
+	}
+}
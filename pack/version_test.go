@@ -0,0 +1,79 @@
+package pack
+
+import (
+	"errors"
+	"github.com/xycczZ/php_pack/target"
+	"testing"
+)
+
+func TestVersions(t *testing.T) {
+	type packCase struct {
+		Format string
+		Args   []any
+		// Accept is false when the version rejects the format
+		Accept bool
+	}
+
+	versions := []struct {
+		Version target.Version
+		Cases   []packCase
+	}{
+		{target.PHP54, []packCase{
+			{"a4", []any{"ab"}, true},
+			{"Z4", []any{"ab"}, false},
+			{"N", []any{1}, true},
+			{"J", []any{1}, false},
+			{"e", []any{1.5}, false},
+		}},
+		{target.PHP55, []packCase{
+			{"Z4", []any{"ab"}, true},
+			{"J", []any{1}, false},
+		}},
+		{target.Version{Major: 5, Minor: 6, Patch: 3}, []packCase{
+			{"J", []any{1}, true},
+			{"Q", []any{1}, true},
+			{"E", []any{1.5}, false},
+		}},
+		{target.Version{Major: 7, Minor: 0, Patch: 14}, []packCase{
+			{"g", []any{1.5}, false},
+		}},
+		{target.Version{Major: 7, Minor: 0, Patch: 15}, []packCase{
+			{"g", []any{1.5}, true},
+			{"E", []any{1.5}, true},
+		}},
+		{target.PHP71, []packCase{
+			{"G", []any{1.5}, false},
+		}},
+		{target.Version{Major: 7, Minor: 1, Patch: 1}, []packCase{
+			{"G", []any{1.5}, true},
+			{"e", []any{1.5}, true},
+		}},
+		{target.PHP80, []packCase{
+			{"Z4J2e", []any{"ab", 1, 2, 1.5}, true},
+			{"y", nil, false},
+		}},
+		{target.PHP84, []packCase{
+			{"Z4J2e", []any{"ab", 1, 2, 1.5}, true},
+		}},
+	}
+
+	for _, v := range versions {
+		for _, c := range v.Cases {
+			option := NewOption(c.Format, c.Args...)
+			option.Version = v.Version
+			_, err := PHPPackWithOption(option)
+			if c.Accept && err != nil {
+				t.Errorf("php %s should accept %s, err: %v\n", v.Version, c.Format, err)
+			}
+			if !c.Accept && !errors.Is(err, ErrFormat) {
+				t.Errorf("php %s should reject %s, err: %v\n", v.Version, c.Format, err)
+			}
+
+			// php before 8.0 also emits a warning for the rejected format
+			warned := len(option.Warnings) == 1 && option.Warnings[0].Msg == "unknown format code"
+			if !c.Accept && warned == v.Version.ThrowsValueError() {
+				t.Errorf("php %s warnings error for %s, actual: %v\n", v.Version, c.Format, option.Warnings)
+			}
+		}
+	}
+}
@@ -0,0 +1,335 @@
+// Package phparray implements an ordered array with the key semantics of php arrays.
+package phparray
+
+import (
+	"fmt"
+	"github.com/xycczZ/php_pack/phpconv"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Key is a php array key, either an integer or a string.
+type Key struct {
+	str   string
+	num   int64
+	isStr bool
+}
+
+// IntKey returns an integer key.
+func IntKey(n int64) Key {
+	return Key{num: n}
+}
+
+// StrKey returns the key php uses for s: decimal integer strings such as "1" or "-5"
+// become integer keys, everything else stays a string key.
+func StrKey(s string) Key {
+	if n, ok := handleNumericStr(s); ok {
+		return Key{num: n}
+	}
+	return Key{str: s, isStr: true}
+}
+
+// IsInt reports whether k is an integer key.
+func (k Key) IsInt() bool {
+	return !k.isStr
+}
+
+// Int returns the integer value of k, 0 for string keys.
+func (k Key) Int() int64 {
+	return k.num
+}
+
+// String returns k as it is printed by php.
+func (k Key) String() string {
+	if k.isStr {
+		return k.str
+	}
+	return strconv.FormatInt(k.num, 10)
+}
+
+// handleNumericStr mirrors ZEND_HANDLE_NUMERIC_STR: only canonical decimal integers
+// (no leading zeros, no '+', no whitespace) that fit in a zend_long are converted.
+func handleNumericStr(s string) (int64, bool) {
+	if len(s) == 0 || len(s) > 20 {
+		return 0, false
+	}
+	digits := s
+	if s[0] == '-' {
+		digits = s[1:]
+	}
+	if len(digits) == 0 || (digits[0] == '0' && len(s) > 1) {
+		return 0, false
+	}
+	for i := 0; i < len(digits); i++ {
+		if digits[i] < '0' || digits[i] > '9' {
+			return 0, false
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// Array is an ordered map like a php array.
+// Keys keep their insertion order, and setting an existing key replaces the value in place.
+type Array struct {
+	keys   []Key
+	values []any
+	index  map[Key]int
+	// nextIndex is the key used by Append, like nNextFreeElement
+	nextIndex int64
+}
+
+// New returns an empty array.
+func New() *Array {
+	return &Array{index: make(map[Key]int)}
+}
+
+// Len returns the number of elements.
+func (a *Array) Len() int {
+	if a == nil {
+		return 0
+	}
+	return len(a.keys)
+}
+
+// Set sets the value of k, appending k when it is not in the array yet.
+func (a *Array) Set(k Key, v any) {
+	if a.index == nil {
+		a.index = make(map[Key]int)
+	}
+	if i, ok := a.index[k]; ok {
+		a.values[i] = v
+		return
+	}
+
+	a.index[k] = len(a.keys)
+	a.keys = append(a.keys, k)
+	a.values = append(a.values, v)
+
+	if k.IsInt() && k.num >= a.nextIndex && k.num < math.MaxInt64 {
+		a.nextIndex = k.num + 1
+	}
+}
+
+// SetString sets the value of the key php derives from s, see StrKey.
+func (a *Array) SetString(s string, v any) {
+	a.Set(StrKey(s), v)
+}
+
+// Append adds v with the next free integer key, like $a[] = v.
+func (a *Array) Append(v any) {
+	a.Set(IntKey(a.nextIndex), v)
+}
+
+// Get returns the value of k.
+func (a *Array) Get(k Key) (any, bool) {
+	if a == nil {
+		return nil, false
+	}
+	i, ok := a.index[k]
+	if !ok {
+		return nil, false
+	}
+	return a.values[i], true
+}
+
+// GetString returns the value of the key php derives from s, see StrKey.
+func (a *Array) GetString(s string) (any, bool) {
+	return a.Get(StrKey(s))
+}
+
+// Delete removes k from the array.
+func (a *Array) Delete(k Key) {
+	if a == nil {
+		return
+	}
+	i, ok := a.index[k]
+	if !ok {
+		return
+	}
+
+	delete(a.index, k)
+	a.keys = append(a.keys[:i], a.keys[i+1:]...)
+	a.values = append(a.values[:i], a.values[i+1:]...)
+	for j := i; j < len(a.keys); j++ {
+		a.index[a.keys[j]] = j
+	}
+}
+
+// Keys returns the keys in order.
+func (a *Array) Keys() []Key {
+	if a == nil {
+		return nil
+	}
+	return append([]Key(nil), a.keys...)
+}
+
+// Values returns the values in order.
+func (a *Array) Values() []any {
+	if a == nil {
+		return nil
+	}
+	return append([]any(nil), a.values...)
+}
+
+// Range calls fn for every element in order until fn returns false.
+func (a *Array) Range(fn func(k Key, v any) bool) {
+	if a == nil {
+		return
+	}
+	for i := range a.keys {
+		if !fn(a.keys[i], a.values[i]) {
+			return
+		}
+	}
+}
+
+// Map converts the array to a plain map, integer keys are formatted in decimal.
+func (a *Array) Map() map[string]any {
+	m := make(map[string]any, a.Len())
+	a.Range(func(k Key, v any) bool {
+		m[k.String()] = v
+		return true
+	})
+	return m
+}
+
+// String formats the array like php's print_r.
+func (a *Array) String() string {
+	var sb strings.Builder
+	a.printR(&sb, 0)
+	return sb.String()
+}
+
+// print_r: php_print_hash / print_hash
+func (a *Array) printR(sb *strings.Builder, indent int) {
+	pad := strings.Repeat(" ", indent)
+	sb.WriteString("Array\n")
+	sb.WriteString(pad)
+	sb.WriteString("(\n")
+
+	a.Range(func(k Key, v any) bool {
+		sb.WriteString(pad)
+		sb.WriteString("    [")
+		sb.WriteString(k.String())
+		sb.WriteString("] => ")
+		if arr, ok := v.(*Array); ok {
+			arr.printR(sb, indent+8)
+			sb.WriteString("\n")
+		} else {
+			sb.WriteString(printValue(v))
+			sb.WriteString("\n")
+		}
+		return true
+	})
+
+	sb.WriteString(pad)
+	sb.WriteString(")\n")
+}
+
+func printValue(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case []byte:
+		return string(x)
+	case bool:
+		if x {
+			return "1"
+		}
+		return ""
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case int:
+		return strconv.Itoa(x)
+	case float64:
+		return formatFloat(x)
+	case float32:
+		return formatFloat(float64(x))
+	case interface{ String() string }:
+		return x.String()
+	}
+	return ""
+}
+
+// formatFloat prints f with php's default precision of 14 significant digits.
+func formatFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NAN"
+	case math.IsInf(f, 1):
+		return "INF"
+	case math.IsInf(f, -1):
+		return "-INF"
+	}
+
+	s := strconv.FormatFloat(f, 'G', 14, 64)
+	if i := strings.IndexByte(s, 'E'); i >= 0 {
+		mantissa, exp := s[:i], s[i+1:]
+		if !strings.Contains(mantissa, ".") {
+			mantissa += ".0"
+		}
+		sign := exp[0]
+		exp = strings.TrimLeft(exp[1:], "0")
+		s = mantissa + "E" + string(sign) + exp
+	}
+	return s
+}
+
+// Dump formats the array like php's var_dump.
+func (a *Array) Dump() string {
+	var sb strings.Builder
+	dumpValue(&sb, a, 1)
+	return sb.String()
+}
+
+// php_var_dump: the value at the given nesting level, elements are indented by two spaces per level
+func dumpValue(sb *strings.Builder, v any, level int) {
+	if level > 1 {
+		sb.WriteString(strings.Repeat(" ", level-1))
+	}
+
+	switch x := v.(type) {
+	case *Array:
+		fmt.Fprintf(sb, "array(%d) {\n", x.Len())
+		x.Range(func(k Key, v any) bool {
+			sb.WriteString(strings.Repeat(" ", level+1))
+			if k.IsInt() {
+				fmt.Fprintf(sb, "[%d]=>\n", k.Int())
+			} else {
+				fmt.Fprintf(sb, "[\"%s\"]=>\n", k.String())
+			}
+			dumpValue(sb, v, level+2)
+			return true
+		})
+		if level > 1 {
+			sb.WriteString(strings.Repeat(" ", level-1))
+		}
+		sb.WriteString("}\n")
+	case nil:
+		sb.WriteString("NULL\n")
+	case bool:
+		fmt.Fprintf(sb, "bool(%t)\n", x)
+	case int64:
+		fmt.Fprintf(sb, "int(%d)\n", x)
+	case int:
+		fmt.Fprintf(sb, "int(%d)\n", x)
+	case float64:
+		fmt.Fprintf(sb, "float(%s)\n", phpconv.FormatFloat(x, phpconv.SerializePrecision))
+	case float32:
+		fmt.Fprintf(sb, "float(%s)\n", phpconv.FormatFloat(float64(x), phpconv.SerializePrecision))
+	case []byte:
+		fmt.Fprintf(sb, "string(%d) \"%s\"\n", len(x), x)
+	case string:
+		fmt.Fprintf(sb, "string(%d) \"%s\"\n", len(x), x)
+	default:
+		s := printValue(v)
+		fmt.Fprintf(sb, "string(%d) \"%s\"\n", len(s), s)
+	}
+}
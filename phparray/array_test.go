@@ -0,0 +1,139 @@
+package phparray
+
+import (
+	"testing"
+)
+
+func TestStrKey(t *testing.T) {
+	cases := []struct {
+		Str   string
+		IsInt bool
+		Int   int64
+	}{
+		{"1", true, 1},
+		{"0", true, 0},
+		{"-5", true, -5},
+		{"9223372036854775807", true, 9223372036854775807},
+		{"9223372036854775808", false, 0},
+		{"01", false, 0},
+		{"-0", false, 0},
+		{"+1", false, 0},
+		{" 1", false, 0},
+		{"1.0", false, 0},
+		{"", false, 0},
+		{"chars1", false, 0},
+	}
+
+	for i := range cases {
+		k := StrKey(cases[i].Str)
+		if k.IsInt() != cases[i].IsInt || k.Int() != cases[i].Int || k.String() != cases[i].Str {
+			t.Errorf("key error, str: %q, expected: %v %d, actual: %v %d\n", cases[i].Str, cases[i].IsInt, cases[i].Int, k.IsInt(), k.Int())
+			return
+		}
+	}
+}
+
+func TestArray(t *testing.T) {
+	a := New()
+	a.SetString("b", 1)
+	a.SetString("1", 2)
+	a.SetString("a", 3)
+	a.Set(IntKey(1), 4)
+	a.Append(5)
+	a.SetString("b", 6)
+
+	expectedKeys := []string{"b", "1", "a", "2"}
+	expectedValues := []any{6, 4, 3, 5}
+	if a.Len() != len(expectedKeys) {
+		t.Errorf("length error, expected: %d, actual: %d\n", len(expectedKeys), a.Len())
+		return
+	}
+
+	i := 0
+	a.Range(func(k Key, v any) bool {
+		if k.String() != expectedKeys[i] || v != expectedValues[i] {
+			t.Errorf("element %d error, expected: %s => %v, actual: %s => %v\n", i, expectedKeys[i], expectedValues[i], k, v)
+		}
+		i++
+		return true
+	})
+
+	if v, ok := a.Get(IntKey(2)); !ok || v != 5 {
+		t.Errorf("get error, actual: %v %v\n", v, ok)
+	}
+	if _, ok := a.GetString("c"); ok {
+		t.Errorf("get should not find key c\n")
+	}
+
+	a.Delete(StrKey("1"))
+	if v, ok := a.GetString("a"); !ok || v != 3 || a.Len() != 3 {
+		t.Errorf("delete error, actual: %v %v %d\n", v, ok, a.Len())
+	}
+
+	m := a.Map()
+	if len(m) != 3 || m["b"] != 6 || m["2"] != 5 {
+		t.Errorf("map error, actual: %v\n", m)
+	}
+}
+
+func TestPrintR(t *testing.T) {
+	inner := New()
+	inner.Append(1.5)
+	inner.Append(1e20)
+
+	a := New()
+	a.SetString("chars1", int64(52))
+	a.SetString("1", []byte("foo"))
+	a.SetString("nested", inner)
+
+	expected := `Array
+(
+    [chars1] => 52
+    [1] => foo
+    [nested] => Array
+        (
+            [0] => 1.5
+            [1] => 1.0E+20
+        )
+
+)
+`
+	if a.String() != expected {
+		t.Errorf("print_r error, expected: %s, actual: %s\n", expected, a.String())
+	}
+}
+
+func TestDump(t *testing.T) {
+	inner := New()
+	inner.Append(1.5)
+	inner.Append(1e20)
+
+	a := New()
+	a.SetString("chars1", int64(52))
+	a.SetString("1", []byte("foo"))
+	a.SetString("nested", inner)
+	a.SetString("nil", nil)
+	a.SetString("ok", true)
+
+	expected := `array(5) {
+  ["chars1"]=>
+  int(52)
+  [1]=>
+  string(3) "foo"
+  ["nested"]=>
+  array(2) {
+    [0]=>
+    float(1.5)
+    [1]=>
+    float(1.0E+20)
+  }
+  ["nil"]=>
+  NULL
+  ["ok"]=>
+  bool(true)
+}
+`
+	if a.Dump() != expected {
+		t.Errorf("var_dump error, expected: %s, actual: %s\n", expected, a.Dump())
+	}
+}
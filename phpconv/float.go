@@ -0,0 +1,132 @@
+package phpconv
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Precision is php's default precision ini, used by the (string) cast and echo.
+	Precision = 14
+	// SerializePrecision is php's default serialize_precision ini: the shortest string
+	// that reads back to the same float, used by serialize, var_export and json_encode.
+	SerializePrecision = -1
+)
+
+// FloatToInt is php's zend_dval_to_lval for a 64-bit zend_long, the (int) cast of a float:
+// the fraction is truncated, NaN and the infinities are 0, values out of range wrap modulo 2^64.
+func FloatToInt(d float64) int64 {
+	const twoPow63 = 9223372036854775808.0
+	const twoPow64 = 18446744073709551616.0
+
+	if math.IsNaN(d) || math.IsInf(d, 0) {
+		return 0
+	}
+	if d >= -twoPow63 && d < twoPow63 {
+		return int64(d)
+	}
+
+	dmod := math.Mod(d, twoPow64)
+	if dmod < 0 {
+		// -2^63 has no positive counterpart
+		if dmod == -twoPow63 {
+			return math.MinInt64
+		}
+		dmod += twoPow64
+	}
+	if dmod >= twoPow63 {
+		dmod -= twoPow64
+	}
+	return int64(dmod)
+}
+
+// FloatToIntCap is php's zend_dval_to_lval_cap, used for numeric strings holding a float:
+// NaN and the infinities are 0, values out of range saturate to the int limits.
+func FloatToIntCap(d float64) int64 {
+	const twoPow63 = 9223372036854775808.0
+
+	if math.IsNaN(d) || math.IsInf(d, 0) {
+		return 0
+	}
+	if d >= twoPow63 {
+		return math.MaxInt64
+	}
+	if d < -twoPow63 {
+		return math.MinInt64
+	}
+	return int64(d)
+}
+
+// FormatFloat formats d like php's zend_gcvt with the given number of significant digits:
+// Precision for the (string) cast, SerializePrecision for the shortest round-trip form.
+// Exponents are written as in php, e.g. "1.0E+25" and "1.0E-5".
+func FormatFloat(d float64, precision int) string {
+	switch {
+	case math.IsNaN(d):
+		return "NAN"
+	case math.IsInf(d, 1):
+		return "INF"
+	case math.IsInf(d, -1):
+		return "-INF"
+	}
+
+	// zend_dtoa mode 0 (shortest) uses 17 digits to choose the notation
+	ndigit, prec := precision, precision-1
+	if precision < 0 {
+		ndigit, prec = 17, -1
+	} else if precision == 0 {
+		ndigit, prec = 1, 0
+	}
+
+	// the significant digits without trailing zeros and the position of the decimal point
+	e := strconv.FormatFloat(math.Abs(d), 'e', prec, 64)
+	mant, exp, _ := strings.Cut(e, "e")
+	decpt, _ := strconv.Atoi(exp)
+	decpt++
+	digits := strings.TrimRight(strings.Replace(mant, ".", "", 1), "0")
+	if digits == "" {
+		digits, decpt = "0", 1
+	}
+
+	var b strings.Builder
+	if math.Signbit(d) {
+		b.WriteByte('-')
+	}
+
+	switch {
+	case decpt < -3 || decpt > ndigit:
+		// exponential format, e.g. 1.0E+25
+		b.WriteByte(digits[0])
+		b.WriteByte('.')
+		if len(digits) == 1 {
+			b.WriteByte('0')
+		} else {
+			b.WriteString(digits[1:])
+		}
+		b.WriteByte('E')
+		decpt--
+		if decpt < 0 {
+			b.WriteByte('-')
+			decpt = -decpt
+		} else {
+			b.WriteByte('+')
+		}
+		b.WriteString(strconv.Itoa(decpt))
+	case decpt <= 0:
+		// 0.000ddd
+		b.WriteString("0.")
+		b.WriteString(strings.Repeat("0", -decpt))
+		b.WriteString(digits)
+	default:
+		if len(digits) <= decpt {
+			b.WriteString(digits)
+			b.WriteString(strings.Repeat("0", decpt-len(digits)))
+		} else {
+			b.WriteString(digits[:decpt])
+			b.WriteByte('.')
+			b.WriteString(digits[decpt:])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,80 @@
+package phpconv
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFloatToInt(t *testing.T) {
+	cases := []struct {
+		F   float64
+		Int int64
+		Cap int64
+	}{
+		{3.7, 3, 3},
+		{-3.7, -3, -3},
+		{1e19, -8446744073709551616, math.MaxInt64},
+		{-1e19, 8446744073709551616, math.MinInt64},
+		{18446744073709551616, 0, math.MaxInt64},
+		{-9223372036854775808, math.MinInt64, math.MinInt64},
+		{math.NaN(), 0, 0},
+		{math.Inf(1), 0, 0},
+		{math.Inf(-1), 0, 0},
+	}
+
+	for _, c := range cases {
+		if i := FloatToInt(c.F); i != c.Int {
+			t.Errorf("FloatToInt(%v) error, expected: %d, actual: %d\n", c.F, c.Int, i)
+		}
+		if i := FloatToIntCap(c.F); i != c.Cap {
+			t.Errorf("FloatToIntCap(%v) error, expected: %d, actual: %d\n", c.F, c.Cap, i)
+		}
+	}
+}
+
+func TestFormatFloat(t *testing.T) {
+	// not folded to the constant 0.3
+	a, b := 0.1, 0.2
+	point3 := a + b
+
+	cases := []struct {
+		F         float64
+		Precision int
+		Expected  string
+	}{
+		{1, Precision, "1"},
+		{1.5, Precision, "1.5"},
+		{-1.5, Precision, "-1.5"},
+		{point3, Precision, "0.3"},
+		{1.0 / 3, Precision, "0.33333333333333"},
+		{100, Precision, "100"},
+		{1e14, Precision, "1.0E+14"},
+		{123456789012345, Precision, "1.2345678901234E+14"},
+		{0.0001, Precision, "0.0001"},
+		{0.00001, Precision, "1.0E-5"},
+		{1.5e-7, Precision, "1.5E-7"},
+		{1e100, Precision, "1.0E+100"},
+		{0, Precision, "0"},
+		{math.Copysign(0, -1), Precision, "-0"},
+		{math.NaN(), Precision, "NAN"},
+		{math.Inf(1), Precision, "INF"},
+		{math.Inf(-1), Precision, "-INF"},
+		{point3, SerializePrecision, "0.30000000000000004"},
+		{0.1, SerializePrecision, "0.1"},
+		{1.0 / 3, SerializePrecision, "0.3333333333333333"},
+		{1e15, SerializePrecision, "1000000000000000"},
+		{1e18, SerializePrecision, "1.0E+18"},
+		{9223372036854775808, SerializePrecision, "9.223372036854776E+18"},
+		{123456789012345678, SerializePrecision, "1.2345678901234568E+17"},
+		{1e-7, SerializePrecision, "1.0E-7"},
+		{float64(float32(0.1)), SerializePrecision, "0.10000000149011612"},
+		{2.6, 1, "3"},
+		{1234.5, 2, "1.2E+3"},
+	}
+
+	for _, c := range cases {
+		if s := FormatFloat(c.F, c.Precision); s != c.Expected {
+			t.Errorf("FormatFloat(%v, %d) error, expected: %s, actual: %s\n", c.F, c.Precision, c.Expected, s)
+		}
+	}
+}
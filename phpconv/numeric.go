@@ -0,0 +1,171 @@
+package phpconv
+
+import (
+	"strconv"
+)
+
+// Kind classifies a string like php 8's is_numeric_string.
+type Kind int
+
+const (
+	// NonNumeric strings such as "abc" or "" do not start with a number.
+	NonNumeric Kind = iota
+	// LeadingNumeric strings such as "12abc" start with a number followed by other data.
+	LeadingNumeric
+	// Numeric strings such as "12", " 1e3" or "3.7 " are a number with optional surrounding whitespace.
+	Numeric
+)
+
+func (k Kind) String() string {
+	switch k {
+	case LeadingNumeric:
+		return "leading-numeric"
+	case Numeric:
+		return "numeric"
+	}
+	return "non-numeric"
+}
+
+// Warning returns the warning php 8 emits when a string of kind k is used in arithmetic:
+// "A non-numeric value encountered" for leading-numeric strings and "" otherwise.
+// Non-numeric strings throw a TypeError instead.
+func (k Kind) Warning() string {
+	if k == LeadingNumeric {
+		return "A non-numeric value encountered"
+	}
+	return ""
+}
+
+// Number is the value of a numeric string: an int, or a float when the string has
+// a fraction, an exponent or does not fit in an int.
+type Number struct {
+	Int     int64
+	Float   float64
+	IsFloat bool
+}
+
+// Float64 returns n as a float.
+func (n Number) Float64() float64 {
+	if n.IsFloat {
+		return n.Float
+	}
+	return float64(n.Int)
+}
+
+// ParseNumber parses the leading number of s like is_numeric_string with errors allowed:
+// leading whitespace is skipped, hexadecimal, octal and binary prefixes are not numbers
+// ("0x1A" is 0 followed by other data), and trailing whitespace is allowed.
+func ParseNumber(s string) (Number, Kind) {
+	start := 0
+	for start < len(s) && isWhitespace(s[start]) {
+		start++
+	}
+
+	end, isFloat := scanNumber(s[start:])
+	if end == 0 {
+		return Number{}, NonNumeric
+	}
+	end += start
+
+	var n Number
+	if !isFloat {
+		i, err := strconv.ParseInt(s[start:end], 10, 64)
+		if err == nil {
+			n.Int = i
+		} else {
+			// too long for an int
+			isFloat = true
+		}
+	}
+	if isFloat {
+		// out of range values are ±Inf or 0 like zend_strtod
+		n.Float, _ = strconv.ParseFloat(s[start:end], 64)
+		n.IsFloat = true
+	}
+
+	for end < len(s) && isWhitespace(s[end]) {
+		end++
+	}
+	if end != len(s) {
+		return n, LeadingNumeric
+	}
+	return n, Numeric
+}
+
+// scanNumber returns the length of the number s starts with, 0 if there is none,
+// and whether it has a fraction or an exponent.
+func scanNumber(s string) (int, bool) {
+	i := 0
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+
+	digits := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+		digits++
+	}
+
+	isFloat := false
+	if i < len(s) && s[i] == '.' {
+		fraction := 0
+		for i+1+fraction < len(s) && isDigit(s[i+1+fraction]) {
+			fraction++
+		}
+		// "1." is a float, "." alone is not a number
+		if digits+fraction > 0 {
+			i += 1 + fraction
+			digits += fraction
+			isFloat = true
+		}
+	}
+	if digits == 0 {
+		return 0, false
+	}
+
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		j := i + 1
+		if j < len(s) && (s[j] == '+' || s[j] == '-') {
+			j++
+		}
+		if j < len(s) && isDigit(s[j]) {
+			for j < len(s) && isDigit(s[j]) {
+				j++
+			}
+			i = j
+			isFloat = true
+		}
+	}
+	return i, isFloat
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isWhitespace is the whitespace is_numeric_string skips: " \t\n\r\v\f".
+func isWhitespace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+}
+
+// StringToInt is php's (int) cast of a string: the leading number, 0 for non-numeric strings,
+// and floats out of range saturate to the int limits.
+func StringToInt(s string) int64 {
+	n, kind := ParseNumber(s)
+	if kind == NonNumeric {
+		return 0
+	}
+	if n.IsFloat {
+		return FloatToIntCap(n.Float)
+	}
+	return n.Int
+}
+
+// StringToFloat is php's (float) cast of a string: the leading number, 0 for non-numeric strings.
+func StringToFloat(s string) float64 {
+	n, kind := ParseNumber(s)
+	if kind == NonNumeric {
+		return 0
+	}
+	return n.Float64()
+}
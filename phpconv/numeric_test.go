@@ -0,0 +1,79 @@
+package phpconv
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseNumber(t *testing.T) {
+	cases := []struct {
+		S      string
+		Number Number
+		Kind   Kind
+	}{
+		{"12", Number{Int: 12}, Numeric},
+		{" 42", Number{Int: 42}, Numeric},
+		{"42 \n", Number{Int: 42}, Numeric},
+		{"-7", Number{Int: -7}, Numeric},
+		{"+7", Number{Int: 7}, Numeric},
+		{"007", Number{Int: 7}, Numeric},
+		{"12abc", Number{Int: 12}, LeadingNumeric},
+		{"0x1A", Number{}, LeadingNumeric},
+		{"1e3", Number{Float: 1000, IsFloat: true}, Numeric},
+		{"1e", Number{Int: 1}, LeadingNumeric},
+		{"1e+", Number{Int: 1}, LeadingNumeric},
+		{"3.7", Number{Float: 3.7, IsFloat: true}, Numeric},
+		{"1.", Number{Float: 1, IsFloat: true}, Numeric},
+		{".5", Number{Float: 0.5, IsFloat: true}, Numeric},
+		{"-.5e-1x", Number{Float: -0.05, IsFloat: true}, LeadingNumeric},
+		{"9223372036854775807", Number{Int: math.MaxInt64}, Numeric},
+		{"-9223372036854775808", Number{Int: math.MinInt64}, Numeric},
+		{"9223372036854775808", Number{Float: 9223372036854775808, IsFloat: true}, Numeric},
+		{"", Number{}, NonNumeric},
+		{" ", Number{}, NonNumeric},
+		{".", Number{}, NonNumeric},
+		{"-", Number{}, NonNumeric},
+		{"abc", Number{}, NonNumeric},
+		{"INF", Number{}, NonNumeric},
+	}
+
+	for _, c := range cases {
+		n, kind := ParseNumber(c.S)
+		if n != c.Number || kind != c.Kind {
+			t.Errorf("parse %q error, expected: %v %s, actual: %v %s\n", c.S, c.Number, c.Kind, n, kind)
+		}
+	}
+
+	if LeadingNumeric.Warning() != "A non-numeric value encountered" || Numeric.Warning() != "" {
+		t.Errorf("leading-numeric strings should warn\n")
+	}
+}
+
+func TestStringConversions(t *testing.T) {
+	cases := []struct {
+		S     string
+		Int   int64
+		Float float64
+	}{
+		{"12abc", 12, 12},
+		{" 42", 42, 42},
+		{"1e3", 1000, 1000},
+		{"3.7", 3, 3.7},
+		{"-3.7", -3, -3.7},
+		{"0x1A", 0, 0},
+		{"abc", 0, 0},
+		{"1e100", math.MaxInt64, 1e100},
+		{"-1e100", math.MinInt64, -1e100},
+		{"99999999999999999999", math.MaxInt64, 1e20},
+		{"1e400", 0, math.Inf(1)},
+	}
+
+	for _, c := range cases {
+		if i := StringToInt(c.S); i != c.Int {
+			t.Errorf("(int)%q error, expected: %d, actual: %d\n", c.S, c.Int, i)
+		}
+		if f := StringToFloat(c.S); f != c.Float {
+			t.Errorf("(float)%q error, expected: %v, actual: %v\n", c.S, c.Float, f)
+		}
+	}
+}
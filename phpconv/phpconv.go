@@ -0,0 +1,265 @@
+// Package phpconv implements php 8's conversions of values to int, float, string and bool,
+// as zval_get_long, zval_get_double, zval_get_string and zend_is_true do.
+//
+// Go values map to php types: nil is null, string and []byte are strings, bool is bool,
+// all integer types are int and float32/float64 are float. Named types convert like their
+// underlying type.
+package phpconv
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// ToInt converts v like php's (int) cast: strings use their leading number,
+// floats are truncated by FloatToInt.
+func ToInt(v any) (int64, error) {
+	switch v := v.(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return int64(v), nil
+	case int8:
+		return int64(v), nil
+	case int16:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case uint:
+		return int64(v), nil
+	case uint8:
+		return int64(v), nil
+	case uint16:
+		return int64(v), nil
+	case uint32:
+		return int64(v), nil
+	case uint64:
+		return int64(v), nil
+	case bool:
+		return boolToInt(v), nil
+	case float32:
+		return FloatToInt(float64(v)), nil
+	case float64:
+		return FloatToInt(v), nil
+	case string:
+		return StringToInt(v), nil
+	case []byte:
+		return StringToInt(string(v)), nil
+	}
+	return toIntReflect(v)
+}
+
+// toIntReflect is ToInt for named types.
+func toIntReflect(v any) (int64, error) {
+	rv := reflect.ValueOf(v)
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		return boolToInt(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return FloatToInt(rv.Float()), nil
+	case reflect.String:
+		return StringToInt(rv.String()), nil
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return StringToInt(string(rv.Bytes())), nil
+		}
+		// an array is 1 unless it is empty
+		return boolToInt(rv.Len() > 0), nil
+	}
+	return 0, fmt.Errorf("can not convert %v to int", v)
+}
+
+// ToFloat converts v like php's (float) cast: strings use their leading number.
+func ToFloat(v any) (float64, error) {
+	switch v := v.(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int8:
+		return float64(v), nil
+	case int16:
+		return float64(v), nil
+	case int32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case uint:
+		return float64(v), nil
+	case uint8:
+		return float64(v), nil
+	case uint16:
+		return float64(v), nil
+	case uint32:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	case bool:
+		return float64(boolToInt(v)), nil
+	case string:
+		return StringToFloat(v), nil
+	case []byte:
+		return StringToFloat(string(v)), nil
+	}
+	return toFloatReflect(v)
+}
+
+// toFloatReflect is ToFloat for named types.
+func toFloatReflect(v any) (float64, error) {
+	rv := reflect.ValueOf(v)
+
+	switch rv.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), nil
+	case reflect.Bool:
+		return float64(boolToInt(rv.Bool())), nil
+	case reflect.String:
+		return StringToFloat(rv.String()), nil
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return StringToFloat(string(rv.Bytes())), nil
+		}
+		return float64(boolToInt(rv.Len() > 0)), nil
+	}
+	return 0, fmt.Errorf("can not convert %v to float", v)
+}
+
+// ToString converts v like php's (string) cast: true is "1", false and null are "",
+// floats are formatted with Precision digits.
+func ToString(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case bool:
+		return boolToString(v), nil
+	case int:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int8:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int16:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int32:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint8:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint16:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float32:
+		return FormatFloat(float64(v), Precision), nil
+	case float64:
+		return FormatFloat(v, Precision), nil
+	}
+	return toStringReflect(v)
+}
+
+// toStringReflect is ToString for named types and fmt.Stringer structs.
+func toStringReflect(v any) (string, error) {
+	rv := reflect.ValueOf(v)
+
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Bool:
+		return boolToString(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return FormatFloat(rv.Float(), Precision), nil
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return string(rv.Bytes()), nil
+		}
+	case reflect.Struct:
+		// objects with __toString
+		if s, ok := v.(fmt.Stringer); ok {
+			return s.String(), nil
+		}
+	}
+	return "", fmt.Errorf("can not convert %v to string", v)
+}
+
+// ToBool converts v like php's (bool) cast: null, 0, 0.0, "", "0" and empty arrays are false.
+func ToBool(v any) (bool, error) {
+	switch v := v.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return v, nil
+	case string:
+		return v != "" && v != "0", nil
+	case []byte:
+		return len(v) != 0 && !(len(v) == 1 && v[0] == '0'), nil
+	case float32:
+		return v != 0, nil
+	case float64:
+		return v != 0, nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Bool:
+		return rv.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int() != 0, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint() != 0, nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float() != 0, nil
+	case reflect.String:
+		s := rv.String()
+		return s != "" && s != "0", nil
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return ToBool(rv.Bytes())
+		}
+		return rv.Len() > 0, nil
+	case reflect.Struct:
+		// objects are true
+		return true, nil
+	}
+	return false, fmt.Errorf("can not convert %v to bool", v)
+}
+
+func boolToInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func boolToString(b bool) string {
+	if b {
+		return "1"
+	}
+	return ""
+}
@@ -0,0 +1,133 @@
+package phpconv
+
+import (
+	"testing"
+)
+
+type namedInt int
+type namedString string
+type namedFloat float32
+type stringer struct{}
+
+func (stringer) String() string {
+	return "object"
+}
+
+func TestToInt(t *testing.T) {
+	cases := []struct {
+		V        any
+		Expected int64
+	}{
+		{nil, 0},
+		{true, 1},
+		{uint64(1<<63 + 1), -1<<63 + 1},
+		{3.99, 3},
+		{float32(-2.5), -2},
+		{"12abc", 12},
+		{[]byte(" 1e3"), 1000},
+		{namedInt(-5), -5},
+		{namedString("0x1A"), 0},
+		{namedFloat(7.5), 7},
+		{[]int{1, 2}, 1},
+		{[]int{}, 0},
+	}
+
+	for _, c := range cases {
+		i, err := ToInt(c.V)
+		if err != nil {
+			t.Errorf("ToInt(%#v) failed: %v\n", c.V, err)
+		} else if i != c.Expected {
+			t.Errorf("ToInt(%#v) error, expected: %d, actual: %d\n", c.V, c.Expected, i)
+		}
+	}
+
+	if _, err := ToInt(struct{}{}); err == nil {
+		t.Errorf("ToInt of a struct should fail\n")
+	}
+}
+
+func TestToFloat(t *testing.T) {
+	cases := []struct {
+		V        any
+		Expected float64
+	}{
+		{nil, 0},
+		{false, 0},
+		{-3, -3},
+		{".5x", 0.5},
+		{"abc", 0},
+		{namedString("2.5e1"), 25},
+		{namedFloat(1.5), 1.5},
+	}
+
+	for _, c := range cases {
+		f, err := ToFloat(c.V)
+		if err != nil {
+			t.Errorf("ToFloat(%#v) failed: %v\n", c.V, err)
+		} else if f != c.Expected {
+			t.Errorf("ToFloat(%#v) error, expected: %v, actual: %v\n", c.V, c.Expected, f)
+		}
+	}
+}
+
+func TestToString(t *testing.T) {
+	cases := []struct {
+		V        any
+		Expected string
+	}{
+		{nil, ""},
+		{true, "1"},
+		{false, ""},
+		{-12, "-12"},
+		{uint64(1 << 63), "9223372036854775808"},
+		{1.5, "1.5"},
+		{float32(0.1), "0.10000000149012"},
+		{1e20, "1.0E+20"},
+		{namedFloat(2), "2"},
+		{namedString("s"), "s"},
+		{stringer{}, "object"},
+	}
+
+	for _, c := range cases {
+		s, err := ToString(c.V)
+		if err != nil {
+			t.Errorf("ToString(%#v) failed: %v\n", c.V, err)
+		} else if s != c.Expected {
+			t.Errorf("ToString(%#v) error, expected: %q, actual: %q\n", c.V, c.Expected, s)
+		}
+	}
+
+	if _, err := ToString([]int{1}); err == nil {
+		t.Errorf("ToString of an array should fail\n")
+	}
+}
+
+func TestToBool(t *testing.T) {
+	cases := []struct {
+		V        any
+		Expected bool
+	}{
+		{nil, false},
+		{"", false},
+		{"0", false},
+		{"0.0", true},
+		{" ", true},
+		{[]byte("0"), false},
+		{0, false},
+		{-1, true},
+		{0.0, false},
+		{0.1, true},
+		{namedString("0"), false},
+		{[]int{}, false},
+		{stringer{}, true},
+	}
+
+	for _, c := range cases {
+		b, err := ToBool(c.V)
+		if err != nil {
+			t.Errorf("ToBool(%#v) failed: %v\n", c.V, err)
+		} else if b != c.Expected {
+			t.Errorf("ToBool(%#v) error, expected: %v, actual: %v\n", c.V, c.Expected, b)
+		}
+	}
+}
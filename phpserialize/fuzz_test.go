@@ -0,0 +1,38 @@
+package phpserialize
+
+import (
+	"testing"
+)
+
+// FuzzUnmarshal checks that unserializing never panics, and that what it accepts serializes
+// to a value that unserializes to the same serialization.
+func FuzzUnmarshal(f *testing.F) {
+	f.Add([]byte(`a:2:{i:0;O:8:"stdClass":1:{s:1:"a";d:0.5;}i:1;r:2;}`))
+	f.Add([]byte(`a:1:{i:0;a:1:{i:0;R:2;}}`))
+	f.Add([]byte(`S:3:"a\62c";`))
+	f.Add([]byte(`C:3:"Foo":5:{hello}`))
+	f.Add([]byte(`E:11:"Suit:Hearts";`))
+	f.Add([]byte(`a:999999999:{`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		v, err := UnmarshalConfig(data, Config{MaxDepth: 64})
+		if err != nil {
+			return
+		}
+		first, err := Marshal(v)
+		if err != nil {
+			// a php reference cycle
+			return
+		}
+
+		v, err = Unmarshal(first)
+		if err != nil {
+			t.Errorf("unmarshal %q failed: %v\n", first, err)
+			return
+		}
+		second, err := Marshal(v)
+		if err != nil || string(second) != string(first) {
+			t.Errorf("round trip %q error, expected: %q, actual: %q %v\n", data, first, second, err)
+		}
+	})
+}
@@ -0,0 +1,385 @@
+// Package phpserialize implements php's serialize() and unserialize() format.
+//
+// php values map to Go values: null is nil, bool is bool, int is int64, float is float64,
+// strings are []byte like unpack's string codes, arrays are *phparray.Array, objects are *Object,
+// objects of Serializable classes (C:) are *Custom and enum cases are Enum.
+//
+// UnmarshalSession and MarshalSession read and write session data in the formats of
+// the php, php_binary and php_serialize session serialize handlers.
+package phpserialize
+
+import (
+	"fmt"
+	"github.com/xycczZ/php_pack/phparray"
+	"github.com/xycczZ/php_pack/phpconv"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// IncompleteClass is the class of objects whose class unserialize did not allow,
+// the original class name is kept in the IncompleteClassName property.
+const (
+	IncompleteClass     = "__PHP_Incomplete_Class"
+	IncompleteClassName = "__PHP_Incomplete_Class_Name"
+)
+
+// Object is a php object, O: in the serialized form.
+type Object struct {
+	Class string
+	// Props holds the properties by their serialized name: "name" for public, "\x00*\x00name" for protected
+	// and "\x00Class\x00name" for private properties. For classes with __serialize it holds
+	// the array __serialize returned, which may have integer keys.
+	Props *phparray.Array
+}
+
+// NewObject returns an object of class without properties.
+func NewObject(class string) *Object {
+	return &Object{Class: class, Props: phparray.New()}
+}
+
+// Get returns the property name, looking for a public, then a protected, then a private property.
+func (o *Object) Get(name string) (any, bool) {
+	if v, ok := o.Props.GetString(name); ok {
+		return v, true
+	}
+	if v, ok := o.Props.GetString("\x00*\x00" + name); ok {
+		return v, true
+	}
+
+	var found any
+	ok := false
+	o.Props.Range(func(k phparray.Key, v any) bool {
+		s := k.String()
+		// "\x00Class\x00name", private to Class or to a parent class
+		if len(s) > len(name)+2 && s[0] == 0 && s[len(s)-len(name)-1] == 0 && s[len(s)-len(name):] == name {
+			found, ok = v, true
+			return false
+		}
+		return true
+	})
+	return found, ok
+}
+
+// Custom is an object of a class implementing Serializable, C: in the serialized form.
+type Custom struct {
+	Class string
+	// Data is what the serialize method returned, unserialize passes it to the unserialize method
+	Data []byte
+}
+
+// Enum is a case of a php 8.1 enum, E: in the serialized form.
+type Enum struct {
+	Class string
+	Case  string
+}
+
+// DefaultMaxDepth is php's default unserialize_max_depth, Marshal and Unmarshal use it
+// to stop on cyclic or too deeply nested values.
+const DefaultMaxDepth = 4096
+
+// Marshal returns the php serialization of v, the string serialize() would return for it.
+//
+// Besides the types Unmarshal returns, v may hold any Go integer, float or string type,
+// maps, which are serialized with their keys sorted, and slices and arrays, which become
+// lists. A *Object or *Custom found again is serialized as a reference to the first one,
+// like php does for objects; arrays are values and are serialized every time.
+func Marshal(v any) ([]byte, error) {
+	e := encoder{objects: make(map[any]int)}
+	if err := e.encode(v); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+type encoder struct {
+	buf []byte
+	// n numbers the values like php's var_hash, objects maps the objects to their number
+	n       int
+	objects map[any]int
+	depth   int
+}
+
+func (e *encoder) encode(v any) error {
+	e.n++
+
+	switch x := v.(type) {
+	case nil:
+		e.buf = append(e.buf, "N;"...)
+	case bool:
+		e.buf = append(e.buf, "b:"...)
+		if x {
+			e.buf = append(e.buf, '1')
+		} else {
+			e.buf = append(e.buf, '0')
+		}
+		e.buf = append(e.buf, ';')
+	case int64:
+		e.encodeInt(x)
+	case int:
+		e.encodeInt(int64(x))
+	case float64:
+		e.encodeFloat(x)
+	case string:
+		e.encodeString(x)
+	case []byte:
+		e.encodeString(string(x))
+	case *phparray.Array:
+		return e.nested(func() error {
+			return e.encodeArray(x)
+		})
+	case *Object:
+		if e.seen(x) {
+			return nil
+		}
+		return e.nested(func() error {
+			return e.encodeObject(x)
+		})
+	case *Custom:
+		if e.seen(x) {
+			return nil
+		}
+		e.buf = append(e.buf, "C:"...)
+		e.appendQuoted(x.Class)
+		e.buf = append(e.buf, ':')
+		e.buf = strconv.AppendInt(e.buf, int64(len(x.Data)), 10)
+		e.buf = append(e.buf, ":{"...)
+		e.buf = append(e.buf, x.Data...)
+		e.buf = append(e.buf, '}')
+	case Enum:
+		e.buf = append(e.buf, "E:"...)
+		e.appendQuoted(x.Class + ":" + x.Case)
+		e.buf = append(e.buf, ';')
+	case *Enum:
+		if x == nil {
+			e.buf = append(e.buf, "N;"...)
+			return nil
+		}
+		e.n--
+		return e.encode(*x)
+	default:
+		return e.encodeReflect(reflect.ValueOf(v))
+	}
+	return nil
+}
+
+// seen writes a reference for an object serialized before, r: refers to it by its number.
+func (e *encoder) seen(obj any) bool {
+	if reflect.ValueOf(obj).IsNil() {
+		e.buf = append(e.buf, "N;"...)
+		return true
+	}
+	if n, ok := e.objects[obj]; ok {
+		e.buf = append(e.buf, "r:"...)
+		e.buf = strconv.AppendInt(e.buf, int64(n), 10)
+		e.buf = append(e.buf, ';')
+		return true
+	}
+	e.objects[obj] = e.n
+	return false
+}
+
+func (e *encoder) nested(fn func() error) error {
+	if e.depth >= DefaultMaxDepth {
+		return fmt.Errorf("phpserialize: maximum depth of %d exceeded, the value may be cyclic", DefaultMaxDepth)
+	}
+	e.depth++
+	err := fn()
+	e.depth--
+	return err
+}
+
+func (e *encoder) encodeInt(n int64) {
+	e.buf = append(e.buf, "i:"...)
+	e.buf = strconv.AppendInt(e.buf, n, 10)
+	e.buf = append(e.buf, ';')
+}
+
+// encodeFloat writes d with serialize_precision -1, the shortest form that reads back the same.
+func (e *encoder) encodeFloat(d float64) {
+	e.buf = append(e.buf, "d:"...)
+	e.buf = append(e.buf, phpconv.FormatFloat(d, phpconv.SerializePrecision)...)
+	e.buf = append(e.buf, ';')
+}
+
+func (e *encoder) encodeString(s string) {
+	e.buf = append(e.buf, "s:"...)
+	e.appendQuoted(s)
+	e.buf = append(e.buf, ';')
+}
+
+// appendQuoted writes the length and the quoted bytes of s: `5:"hello"`.
+func (e *encoder) appendQuoted(s string) {
+	e.buf = strconv.AppendInt(e.buf, int64(len(s)), 10)
+	e.buf = append(e.buf, ":\""...)
+	e.buf = append(e.buf, s...)
+	e.buf = append(e.buf, '"')
+}
+
+func (e *encoder) appendKey(k phparray.Key) {
+	if k.IsInt() {
+		e.encodeInt(k.Int())
+	} else {
+		e.encodeString(k.String())
+	}
+}
+
+func (e *encoder) encodeArray(a *phparray.Array) error {
+	e.buf = append(e.buf, "a:"...)
+	e.buf = strconv.AppendInt(e.buf, int64(a.Len()), 10)
+	e.buf = append(e.buf, ":{"...)
+	if err := e.encodeElements(a, ""); err != nil {
+		return err
+	}
+	e.buf = append(e.buf, '}')
+	return nil
+}
+
+// encodeElements writes the keys and values of a, leaving out the key skip.
+func (e *encoder) encodeElements(a *phparray.Array, skip string) error {
+	var err error
+	a.Range(func(k phparray.Key, v any) bool {
+		if skip != "" && !k.IsInt() && k.String() == skip {
+			return true
+		}
+		e.appendKey(k)
+		err = e.encode(v)
+		return err == nil
+	})
+	return err
+}
+
+func (e *encoder) encodeObject(o *Object) error {
+	class, skip, count := o.Class, "", o.Props.Len()
+	// an incomplete object is serialized as the object it was
+	if class == IncompleteClass {
+		if name, ok := o.Props.GetString(IncompleteClassName); ok {
+			if s, ok := name.([]byte); ok {
+				class, skip, count = string(s), IncompleteClassName, count-1
+			} else if s, ok := name.(string); ok {
+				class, skip, count = s, IncompleteClassName, count-1
+			}
+		}
+	}
+
+	e.buf = append(e.buf, "O:"...)
+	e.appendQuoted(class)
+	e.buf = append(e.buf, ':')
+	e.buf = strconv.AppendInt(e.buf, int64(count), 10)
+	e.buf = append(e.buf, ":{"...)
+	if err := e.encodeElements(o.Props, skip); err != nil {
+		return err
+	}
+	e.buf = append(e.buf, '}')
+	return nil
+}
+
+// encodeReflect serializes the other integer, float and string types, maps, slices and arrays.
+func (e *encoder) encodeReflect(rv reflect.Value) error {
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.encodeInt(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		// php has no unsigned integers, large values wrap like phpconv.ToInt
+		e.encodeInt(int64(rv.Uint()))
+	case reflect.Float32, reflect.Float64:
+		e.encodeFloat(rv.Float())
+	case reflect.Bool:
+		e.n--
+		return e.encode(rv.Bool())
+	case reflect.String:
+		e.encodeString(rv.String())
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+			e.encodeString(string(rv.Bytes()))
+			return nil
+		}
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			e.buf = append(e.buf, "N;"...)
+			return nil
+		}
+		return e.nested(func() error {
+			return e.encodeList(rv)
+		})
+	case reflect.Map:
+		if rv.IsNil() {
+			e.buf = append(e.buf, "N;"...)
+			return nil
+		}
+		return e.nested(func() error {
+			return e.encodeMap(rv)
+		})
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			e.buf = append(e.buf, "N;"...)
+			return nil
+		}
+		e.n--
+		return e.encode(rv.Elem().Interface())
+	default:
+		return fmt.Errorf("phpserialize: can not serialize %s", rv.Type())
+	}
+	return nil
+}
+
+func (e *encoder) encodeList(rv reflect.Value) error {
+	e.buf = append(e.buf, "a:"...)
+	e.buf = strconv.AppendInt(e.buf, int64(rv.Len()), 10)
+	e.buf = append(e.buf, ":{"...)
+	for i := 0; i < rv.Len(); i++ {
+		e.encodeInt(int64(i))
+		if err := e.encode(rv.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+	e.buf = append(e.buf, '}')
+	return nil
+}
+
+// encodeMap writes a map as an array, integer keys in numeric order before string keys in byte order.
+func (e *encoder) encodeMap(rv reflect.Value) error {
+	type entry struct {
+		key   phparray.Key
+		value reflect.Value
+	}
+
+	entries := make([]entry, 0, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		k := iter.Key()
+		var key phparray.Key
+		switch k.Kind() {
+		case reflect.String:
+			key = phparray.StrKey(k.String())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			key = phparray.IntKey(k.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			key = phparray.IntKey(int64(k.Uint()))
+		default:
+			return fmt.Errorf("phpserialize: can not serialize map key %s", k.Type())
+		}
+		entries = append(entries, entry{key, iter.Value()})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i].key, entries[j].key
+		if a.IsInt() != b.IsInt() {
+			return a.IsInt()
+		}
+		if a.IsInt() {
+			return a.Int() < b.Int()
+		}
+		return a.String() < b.String()
+	})
+
+	e.buf = append(e.buf, "a:"...)
+	e.buf = strconv.AppendInt(e.buf, int64(len(entries)), 10)
+	e.buf = append(e.buf, ":{"...)
+	for _, en := range entries {
+		e.appendKey(en.key)
+		if err := e.encode(en.value.Interface()); err != nil {
+			return err
+		}
+	}
+	e.buf = append(e.buf, '}')
+	return nil
+}
@@ -0,0 +1,109 @@
+package phpserialize
+
+import (
+	"github.com/xycczZ/php_pack/phparray"
+	"math"
+	"testing"
+)
+
+type level uint8
+
+func TestMarshal(t *testing.T) {
+	list := phparray.New()
+	list.Append(int64(1))
+	list.SetString("a", []byte("x"))
+	list.SetString("1", nil)
+
+	foo := NewObject("Foo")
+	foo.Props.SetString("a", int64(1))
+	foo.Props.SetString("\x00*\x00b", int64(2))
+	foo.Props.SetString("\x00Foo\x00c", int64(3))
+
+	incomplete := NewObject(IncompleteClass)
+	incomplete.Props.SetString(IncompleteClassName, []byte("Bar"))
+	incomplete.Props.SetString("x", true)
+
+	cases := []struct {
+		Value    any
+		Expected string
+	}{
+		{nil, `N;`},
+		{true, `b:1;`},
+		{false, `b:0;`},
+		{42, `i:42;`},
+		{int64(-7), `i:-7;`},
+		{level(3), `i:3;`},
+		{uint64(math.MaxUint64), `i:-1;`},
+		{0.1, `d:0.1;`},
+		{1.0, `d:1;`},
+		{float32(0.5), `d:0.5;`},
+		{math.Copysign(0, -1), `d:-0;`},
+		{1e25, `d:1.0E+25;`},
+		{math.Inf(-1), `d:-INF;`},
+		{math.NaN(), `d:NAN;`},
+		{"héllo", `s:6:"héllo";`},
+		{[]byte("a\"b"), `s:3:"a"b";`},
+		{list, `a:3:{i:0;i:1;s:1:"a";s:1:"x";i:1;N;}`},
+		{[]int{5, 6}, `a:2:{i:0;i:5;i:1;i:6;}`},
+		{[2]string{"x", "y"}, `a:2:{i:0;s:1:"x";i:1;s:1:"y";}`},
+		{map[string]any{"b": 1, "a": 2, "10": 3, "9": 4}, `a:4:{i:9;i:4;i:10;i:3;s:1:"a";i:2;s:1:"b";i:1;}`},
+		{map[int]bool{}, `a:0:{}`},
+		{[]any(nil), `N;`},
+		{foo, "O:3:\"Foo\":3:{s:1:\"a\";i:1;s:4:\"\x00*\x00b\";i:2;s:6:\"\x00Foo\x00c\";i:3;}"},
+		{&Object{Class: "stdClass"}, `O:8:"stdClass":0:{}`},
+		{incomplete, `O:3:"Bar":1:{s:1:"x";b:1;}`},
+		{&Custom{Class: "Foo", Data: []byte("hello")}, `C:3:"Foo":5:{hello}`},
+		{Enum{Class: "Suit", Case: "Hearts"}, `E:11:"Suit:Hearts";`},
+		{(*Object)(nil), `N;`},
+	}
+
+	for _, c := range cases {
+		actual, err := Marshal(c.Value)
+		if err != nil {
+			t.Errorf("marshal %#v failed: %v\n", c.Value, err)
+			continue
+		}
+		if string(actual) != c.Expected {
+			t.Errorf("marshal %#v error, expected: %q, actual: %q\n", c.Value, c.Expected, actual)
+		}
+	}
+}
+
+func TestMarshalReferences(t *testing.T) {
+	// $o = new stdClass; serialize([$o, [$o], $c, $c]);
+	o := NewObject("stdClass")
+	c := &Custom{Class: "Foo", Data: []byte("x")}
+	actual, err := Marshal([]any{o, []any{o}, c, c})
+	if err != nil {
+		t.Errorf("marshal failed: %v\n", err)
+		return
+	}
+
+	expected := `a:4:{i:0;O:8:"stdClass":0:{}i:1;a:1:{i:0;r:2;}i:2;C:3:"Foo":1:{x}i:3;r:5;}`
+	if string(actual) != expected {
+		t.Errorf("marshal error, expected: %q, actual: %q\n", expected, actual)
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	cyclic := phparray.New()
+	cyclic.Append(cyclic)
+
+	cases := []struct {
+		Value any
+		Err   string
+	}{
+		{cyclic, "phpserialize: maximum depth of 4096 exceeded, the value may be cyclic"},
+		{struct{}{}, "phpserialize: can not serialize struct {}"},
+		{complex(1, 2), "phpserialize: can not serialize complex128"},
+		{map[float64]int{1: 1}, "phpserialize: can not serialize map key float64"},
+		{[]any{make(chan int)}, "phpserialize: can not serialize chan int"},
+	}
+
+	for _, c := range cases {
+		_, err := Marshal(c.Value)
+		if err == nil || err.Error() != c.Err {
+			t.Errorf("marshal %T error, expected: %s, actual: %v\n", c.Value, c.Err, err)
+		}
+	}
+}
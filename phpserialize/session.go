@@ -0,0 +1,178 @@
+package phpserialize
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/xycczZ/php_pack/pack"
+	"github.com/xycczZ/php_pack/phparray"
+	"strings"
+)
+
+// Handler is a session serialize handler, the format of session.serialize_handler.
+type Handler int
+
+const (
+	// HandlerPHP writes every variable as its name, '|' and its serialized value, php's default.
+	HandlerPHP Handler = iota
+	// HandlerPHPBinary writes every variable as the length of its name in a byte, the name and its serialized value.
+	HandlerPHPBinary
+	// HandlerPHPSerialize writes the serialization of the whole $_SESSION array.
+	HandlerPHPSerialize
+)
+
+// binaryMaxName is PS_BIN_MAX, the longest name php_binary can write, the high bit of the length byte is ignored.
+const binaryMaxName = 127
+
+// binaryName writes the length byte and the name of a php_binary variable.
+var binaryName = mustCompile(pack.Compile("Ca*"))
+
+// mustCompile returns the compiled form of a constant format and panics when it does not compile, like regexp.MustCompile.
+func mustCompile[F any](f F, err error) F {
+	if err != nil {
+		panic("phpserialize: " + err.Error())
+	}
+	return f
+}
+
+var handlerNames = []string{"php", "php_binary", "php_serialize"}
+
+func (h Handler) String() string {
+	if h < 0 || int(h) >= len(handlerNames) {
+		return fmt.Sprintf("Handler(%d)", int(h))
+	}
+	return handlerNames[h]
+}
+
+// ParseHandler returns the handler of a session.serialize_handler name: "php", "php_binary" or "php_serialize".
+func ParseHandler(name string) (Handler, error) {
+	for i, n := range handlerNames {
+		if n == name {
+			return Handler(i), nil
+		}
+	}
+	return 0, fmt.Errorf("phpserialize: unknown session serialize handler %q", name)
+}
+
+// UnmarshalSession parses session data written by handler, see UnmarshalSessionConfig.
+func UnmarshalSession(data []byte, handler Handler) (*phparray.Array, error) {
+	return UnmarshalSessionConfig(data, handler, Config{})
+}
+
+// UnmarshalSessionConfig parses session data written by handler into the $_SESSION array, limited by config.
+//
+// Like session_decode(), the values of the php and php_binary handlers are numbered as one
+// serialization, so r: and R: may refer to the values of earlier variables. Empty data is an empty session.
+// The php handler stops quietly at data without a '|' after the last variable, as session_decode() does.
+func UnmarshalSessionConfig(data []byte, handler Handler, config Config) (*phparray.Array, error) {
+	if config.MaxDepth == 0 {
+		config.MaxDepth = DefaultMaxDepth
+	}
+	d := decoder{data: data, config: &config}
+	session := phparray.New()
+	if len(data) == 0 {
+		return session, nil
+	}
+
+	switch handler {
+	case HandlerPHP:
+		for d.pos < len(data) {
+			i := bytes.IndexByte(data[d.pos:], '|')
+			if i < 0 {
+				// like php, the rest of the data without a '|' is ignored
+				break
+			}
+			name := string(data[d.pos : d.pos+i])
+			d.pos += i + 1
+
+			v, err := d.value()
+			if err != nil {
+				return nil, err
+			}
+			session.SetString(name, v)
+		}
+	case HandlerPHPBinary:
+		for d.pos < len(data) {
+			n := int(data[d.pos]) & binaryMaxName
+			if n >= len(data)-d.pos-1 {
+				return nil, d.error("variable name of %d bytes and a value, %d bytes left", n, len(data)-d.pos-1)
+			}
+			name := string(data[d.pos+1 : d.pos+1+n])
+			d.pos += 1 + n
+
+			v, err := d.value()
+			if err != nil {
+				return nil, err
+			}
+			session.SetString(name, v)
+		}
+	case HandlerPHPSerialize:
+		v, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		if d.pos < len(data) {
+			return nil, d.error("extra data after the value")
+		}
+		a, ok := v.(*phparray.Array)
+		if !ok {
+			d.pos = 0
+			return nil, d.error("session data is %T, not an array", v)
+		}
+		session = a
+	default:
+		return nil, fmt.Errorf("phpserialize: unknown session serialize handler %d", int(handler))
+	}
+	return session, nil
+}
+
+// MarshalSession returns the session data handler writes for the $_SESSION array session, what session_encode() returns.
+//
+// The values of the php and php_binary handlers are numbered as one serialization, a *Object
+// or *Custom found again refers to the first one. php skips integer keys with a notice, they are
+// written as their decimal name here so what UnmarshalSession returns for "5|i:1;" is written back.
+// A php name can not contain '|' and a php_binary name is at most 127 bytes, other names are an error.
+func MarshalSession(session *phparray.Array, handler Handler) ([]byte, error) {
+	e := encoder{objects: make(map[any]int)}
+	if handler == HandlerPHPSerialize {
+		if session == nil {
+			session = phparray.New()
+		}
+		if err := e.encode(session); err != nil {
+			return nil, err
+		}
+		return e.buf, nil
+	}
+	if handler != HandlerPHP && handler != HandlerPHPBinary {
+		return nil, fmt.Errorf("phpserialize: unknown session serialize handler %d", int(handler))
+	}
+	if session == nil {
+		return nil, nil
+	}
+
+	var err error
+	session.Range(func(k phparray.Key, v any) bool {
+		name := k.String()
+		if handler == HandlerPHP {
+			if strings.IndexByte(name, '|') >= 0 {
+				err = fmt.Errorf("phpserialize: session variable name %q contains '|'", name)
+				return false
+			}
+			e.buf = append(e.buf, name...)
+			e.buf = append(e.buf, '|')
+		} else {
+			if len(name) > binaryMaxName {
+				err = fmt.Errorf("phpserialize: session variable name of %d bytes, php_binary allows at most %d", len(name), binaryMaxName)
+				return false
+			}
+			if e.buf, err = binaryName.Append(e.buf, len(name), name); err != nil {
+				return false
+			}
+		}
+		err = e.encode(v)
+		return err == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
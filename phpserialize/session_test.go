@@ -0,0 +1,147 @@
+package phpserialize
+
+import (
+	"errors"
+	"github.com/xycczZ/php_pack/pack"
+	"github.com/xycczZ/php_pack/phparray"
+	"strings"
+	"testing"
+)
+
+func TestSession(t *testing.T) {
+	// $_SESSION = ['name' => 'alice', 'id' => 7, 'obj' => $o = new stdClass, 'same' => $o];
+	cases := []struct {
+		Handler Handler
+		Data    string
+	}{
+		{HandlerPHP, `name|s:5:"alice";id|i:7;obj|O:8:"stdClass":0:{}same|r:3;`},
+		{HandlerPHPBinary, "\x04name" + `s:5:"alice";` + "\x02id" + `i:7;` + "\x03obj" + `O:8:"stdClass":0:{}` + "\x04same" + `r:3;`},
+		{HandlerPHPSerialize, `a:4:{s:4:"name";s:5:"alice";s:2:"id";i:7;s:3:"obj";O:8:"stdClass":0:{}s:4:"same";r:4;}`},
+	}
+
+	for _, c := range cases {
+		session, err := UnmarshalSession([]byte(c.Data), c.Handler)
+		if err != nil {
+			t.Errorf("unmarshal %s session failed: %v\n", c.Handler, err)
+			continue
+		}
+		if name, _ := session.GetString("name"); string(name.([]byte)) != "alice" {
+			t.Errorf("%s session error, expected: name alice, actual: %v\n", c.Handler, session)
+		}
+		obj, _ := session.GetString("obj")
+		same, _ := session.GetString("same")
+		if _, ok := obj.(*Object); !ok || obj != same {
+			t.Errorf("%s session error, expected: one shared object, actual: %#v %#v\n", c.Handler, obj, same)
+		}
+
+		actual, err := MarshalSession(session, c.Handler)
+		if err != nil {
+			t.Errorf("marshal %s session failed: %v\n", c.Handler, err)
+			continue
+		}
+		if string(actual) != c.Data {
+			t.Errorf("marshal %s session error, expected: %q, actual: %q\n", c.Handler, c.Data, actual)
+		}
+	}
+}
+
+func TestSessionEdges(t *testing.T) {
+	for _, h := range []Handler{HandlerPHP, HandlerPHPBinary, HandlerPHPSerialize} {
+		session, err := UnmarshalSession(nil, h)
+		if err != nil || session.Len() != 0 {
+			t.Errorf("unmarshal empty %s session error, expected: empty array, actual: %v %v\n", h, session, err)
+		}
+	}
+
+	// the high bit of the php_binary length is ignored
+	session, err := UnmarshalSession([]byte("\x81a"+`i:1;`), HandlerPHPBinary)
+	if v, _ := session.GetString("a"); err != nil || v != int64(1) {
+		t.Errorf("unmarshal php_binary error, expected: a 1, actual: %v %v\n", session, err)
+	}
+
+	// php stops at a name without '|' and keeps the variables before it
+	for data, expected := range map[string]int{`name`: 0, `a|i:1;b`: 1} {
+		session, err := UnmarshalSession([]byte(data), HandlerPHP)
+		if err != nil || session.Len() != expected {
+			t.Errorf("unmarshal php session %q error, expected: %d variables, actual: %v %v\n", data, expected, session, err)
+		}
+	}
+
+	list := phparray.New()
+	list.Append(true)
+	data, err := MarshalSession(list, HandlerPHP)
+	if err != nil || string(data) != `0|b:1;` {
+		t.Errorf("marshal integer key error, expected: %q, actual: %q %v\n", `0|b:1;`, data, err)
+	}
+	data, err = MarshalSession(nil, HandlerPHPSerialize)
+	if err != nil || string(data) != `a:0:{}` {
+		t.Errorf("marshal nil session error, expected: %q, actual: %q %v\n", `a:0:{}`, data, err)
+	}
+
+	for _, name := range []string{"php", "php_binary", "php_serialize"} {
+		h, err := ParseHandler(name)
+		if err != nil || h.String() != name {
+			t.Errorf("parse handler %s error, actual: %v %v\n", name, h, err)
+		}
+	}
+	if _, err := ParseHandler("igbinary"); err == nil {
+		t.Errorf("parse handler igbinary error, expected: an error\n")
+	}
+}
+
+func TestSessionErrors(t *testing.T) {
+	cases := []struct {
+		Handler Handler
+		Data    string
+		Offset  int
+		Msg     string
+	}{
+		{HandlerPHP, `a|x`, 2, "unexpected end of input"},
+		{HandlerPHPBinary, "\x05ab", 0, "variable name of 5 bytes and a value, 2 bytes left"},
+		{HandlerPHPBinary, "\x01ai:1", 5, "unexpected end of input, want ';'"},
+		{HandlerPHPSerialize, `i:1;`, 0, "session data is int64, not an array"},
+		{HandlerPHPSerialize, `a:0:{}x`, 6, "extra data after the value"},
+	}
+
+	for _, c := range cases {
+		_, err := UnmarshalSession([]byte(c.Data), c.Handler)
+		var se *SyntaxError
+		if !errors.As(err, &se) || se.Offset != c.Offset || se.Msg != c.Msg {
+			t.Errorf("unmarshal %s session %q error, expected: %d %s, actual: %v\n", c.Handler, c.Data, c.Offset, c.Msg, err)
+		}
+	}
+
+	_, err := UnmarshalSessionConfig([]byte(`a|i:1;b|i:2;`), HandlerPHP, Config{MaxElements: 1})
+	if err == nil || err.Error() != "phpserialize: error at offset 8 of 12 bytes: more than 1 values" {
+		t.Errorf("unmarshal session with max elements error, actual: %v\n", err)
+	}
+
+	pipe := phparray.New()
+	pipe.SetString("a|b", nil)
+	long := phparray.New()
+	long.SetString(strings.Repeat("x", 128), nil)
+	marshal := []struct {
+		Session *phparray.Array
+		Handler Handler
+		Err     string
+	}{
+		{pipe, HandlerPHP, `phpserialize: session variable name "a|b" contains '|'`},
+		{long, HandlerPHPBinary, "phpserialize: session variable name of 128 bytes, php_binary allows at most 127"},
+		{pipe, Handler(5), "phpserialize: unknown session serialize handler 5"},
+	}
+	for _, c := range marshal {
+		_, err := MarshalSession(c.Session, c.Handler)
+		if err == nil || err.Error() != c.Err {
+			t.Errorf("marshal %s session error, expected: %s, actual: %v\n", c.Handler, c.Err, err)
+		}
+	}
+}
+
+func TestMustCompile(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(r.(string), "unknown format code") {
+			t.Errorf("must compile error, expected: a panic, actual: %v\n", r)
+		}
+	}()
+	mustCompile(pack.Compile("y"))
+}
@@ -0,0 +1,542 @@
+package phpserialize
+
+import (
+	"fmt"
+	"github.com/xycczZ/php_pack/phparray"
+	"math"
+	"slices"
+	"strconv"
+)
+
+// Config limits what Unmarshal accepts, the zero value applies php's defaults.
+type Config struct {
+	// MaxDepth is the maximum nesting of arrays and objects, like unserialize's max_depth option:
+	// 0 means DefaultMaxDepth and a negative value disables the limit
+	MaxDepth int
+	// MaxElements is the maximum number of values in the input, 0 disables the limit.
+	// The element count of every array and object is also checked against the input left,
+	// so input of n bytes never allocates more than about n elements.
+	MaxElements int
+	// AllowedClasses are the classes objects may have, like unserialize's allowed_classes option.
+	// nil allows every class; objects of other classes are decoded as IncompleteClass objects,
+	// and enums of other classes are an error.
+	AllowedClasses []string
+}
+
+// SyntaxError is input that is not a valid serialized value or exceeds a limit of the Config.
+type SyntaxError struct {
+	// Offset is the position in the input the error was found at, Len the length of the input
+	Offset int
+	Len    int
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("phpserialize: error at offset %d of %d bytes: %s", e.Offset, e.Len, e.Msg)
+}
+
+// Unmarshal parses the serialized value data, see UnmarshalConfig.
+func Unmarshal(data []byte) (any, error) {
+	return UnmarshalConfig(data, Config{})
+}
+
+// UnmarshalConfig parses the serialized value data like php's unserialize(), limited by config.
+//
+// References decode to the value they refer to: r: and R: to an object or an array share it,
+// to other values copy it. A php reference (&) between two values is therefore not kept
+// once one of them is a scalar. Data after the value is an error, php 8.3 warns about it.
+func UnmarshalConfig(data []byte, config Config) (any, error) {
+	if config.MaxDepth == 0 {
+		config.MaxDepth = DefaultMaxDepth
+	}
+	d := decoder{data: data, config: &config}
+
+	v, err := d.value()
+	if err != nil {
+		return nil, err
+	}
+	if d.pos < len(data) {
+		return nil, d.error("extra data after the value")
+	}
+	return v, nil
+}
+
+type decoder struct {
+	data   []byte
+	pos    int
+	config *Config
+	// slots are the values r: and R: refer to, numbered from 1 like php's var_hash
+	slots []any
+	depth int
+}
+
+func (d *decoder) error(format string, args ...any) error {
+	return &SyntaxError{Offset: d.pos, Len: len(d.data), Msg: fmt.Sprintf(format, args...)}
+}
+
+// push numbers a value, arrays and objects are numbered before their elements.
+func (d *decoder) push(v any) (int, error) {
+	if d.config.MaxElements > 0 && len(d.slots) >= d.config.MaxElements {
+		return 0, d.error("more than %d values", d.config.MaxElements)
+	}
+	d.slots = append(d.slots, v)
+	return len(d.slots) - 1, nil
+}
+
+func (d *decoder) value() (any, error) {
+	if d.pos+1 >= len(d.data) {
+		return nil, d.error("unexpected end of input")
+	}
+	code := d.data[d.pos]
+	if code == 'R' {
+		return d.reference()
+	}
+
+	slot, err := d.push(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var v any
+	switch code {
+	case 'N':
+		d.pos++
+		err = d.expect(';')
+	case 'b':
+		d.pos++
+		if err = d.expect(':'); err == nil {
+			if d.pos < len(d.data) && (d.data[d.pos] == '0' || d.data[d.pos] == '1') {
+				v = d.data[d.pos] == '1'
+				d.pos++
+				err = d.expect(';')
+			} else {
+				err = d.error("bool must be 0 or 1")
+			}
+		}
+	case 'i':
+		d.pos++
+		if err = d.expect(':'); err == nil {
+			v, err = d.int(';')
+		}
+	case 'd':
+		d.pos++
+		if err = d.expect(':'); err == nil {
+			v, err = d.float()
+		}
+	case 's', 'S':
+		d.pos++
+		var s []byte
+		if err = d.expect(':'); err == nil {
+			s, err = d.str(code == 'S')
+		}
+		if err == nil {
+			v, err = s, d.expect(';')
+		}
+	case 'a':
+		v, err = d.array(slot)
+	case 'O':
+		v, err = d.object(slot)
+	case 'C':
+		v, err = d.custom()
+	case 'E':
+		v, err = d.enum()
+	case 'r':
+		d.pos++
+		if err = d.expect(':'); err == nil {
+			// not to itself
+			v, err = d.ref(len(d.slots) - 1)
+		}
+	default:
+		return nil, d.error("unexpected %q", code)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	d.slots[slot] = v
+	return v, nil
+}
+
+// reference decodes R:, a php reference, which is not numbered itself.
+func (d *decoder) reference() (any, error) {
+	d.pos++
+	if err := d.expect(':'); err != nil {
+		return nil, err
+	}
+	return d.ref(len(d.slots))
+}
+
+// ref reads the number of one of the first n values and returns the value.
+func (d *decoder) ref(n int) (any, error) {
+	start := d.pos
+	id, err := d.uint(';')
+	if err != nil {
+		return nil, err
+	}
+	if id < 1 || id > n {
+		d.pos = start
+		return nil, d.error("reference to value %d of %d", id, n)
+	}
+	return d.slots[id-1], nil
+}
+
+func (d *decoder) expect(c byte) error {
+	if d.pos >= len(d.data) {
+		return d.error("unexpected end of input, want %q", c)
+	}
+	if d.data[d.pos] != c {
+		return d.error("unexpected %q, want %q", d.data[d.pos], c)
+	}
+	d.pos++
+	return nil
+}
+
+// digits returns the digits from pos on, optionally after a sign.
+func (d *decoder) digits(sign bool) []byte {
+	start := d.pos
+	if sign && d.pos < len(d.data) && (d.data[d.pos] == '-' || d.data[d.pos] == '+') {
+		d.pos++
+	}
+	for d.pos < len(d.data) && d.data[d.pos] >= '0' && d.data[d.pos] <= '9' {
+		d.pos++
+	}
+	return d.data[start:d.pos]
+}
+
+// int reads an integer and the byte ending it.
+func (d *decoder) int(end byte) (int64, error) {
+	start := d.pos
+	s := d.digits(true)
+	n, err := strconv.ParseInt(string(s), 10, 64)
+	if err != nil {
+		d.pos = start
+		if len(s) > 0 && s[len(s)-1] >= '0' && s[len(s)-1] <= '9' {
+			return 0, d.error("integer %s out of range", s)
+		}
+		return 0, d.error("invalid integer")
+	}
+	return n, d.expect(end)
+}
+
+// uint reads a count or a length and the byte ending it.
+func (d *decoder) uint(end byte) (int, error) {
+	start := d.pos
+	s := d.digits(false)
+	n, err := strconv.ParseUint(string(s), 10, 63)
+	if err != nil || n > math.MaxInt {
+		d.pos = start
+		return 0, d.error("invalid length")
+	}
+	return int(n), d.expect(end)
+}
+
+// float reads the php float syntax: an integer, a decimal with an optional exponent, INF, -INF or NAN.
+func (d *decoder) float() (float64, error) {
+	start := d.pos
+	for _, special := range []string{"NAN", "INF", "-INF"} {
+		if d.hasPrefix(special + ";") {
+			d.pos += len(special) + 1
+			switch special {
+			case "NAN":
+				return math.NaN(), nil
+			case "INF":
+				return math.Inf(1), nil
+			}
+			return math.Inf(-1), nil
+		}
+	}
+
+	if d.pos < len(d.data) && (d.data[d.pos] == '-' || d.data[d.pos] == '+') {
+		d.pos++
+	}
+	digits := len(d.digits(false))
+	if d.pos < len(d.data) && d.data[d.pos] == '.' {
+		d.pos++
+		digits += len(d.digits(false))
+	}
+	if digits == 0 {
+		d.pos = start
+		return 0, d.error("invalid float")
+	}
+	if d.pos < len(d.data) && (d.data[d.pos] == 'e' || d.data[d.pos] == 'E') {
+		d.pos++
+		if exp := d.digits(true); len(exp) == 0 || exp[len(exp)-1] < '0' || exp[len(exp)-1] > '9' {
+			return 0, d.error("invalid float exponent")
+		}
+	}
+
+	f, err := strconv.ParseFloat(string(d.data[start:d.pos]), 64)
+	if err != nil && !isRangeError(err) {
+		d.pos = start
+		return 0, d.error("invalid float")
+	}
+	// like strtod, out of range values are ±INF or 0
+	return f, d.expect(';')
+}
+
+func isRangeError(err error) bool {
+	ne, ok := err.(*strconv.NumError)
+	return ok && ne.Err == strconv.ErrRange
+}
+
+func (d *decoder) hasPrefix(s string) bool {
+	return len(d.data)-d.pos >= len(s) && string(d.data[d.pos:d.pos+len(s)]) == s
+}
+
+// str reads `len:"bytes"`, S: strings escape bytes as \hh and count the decoded length.
+func (d *decoder) str(escaped bool) ([]byte, error) {
+	n, err := d.uint(':')
+	if err != nil {
+		return nil, err
+	}
+	if err := d.expect('"'); err != nil {
+		return nil, err
+	}
+
+	var s []byte
+	if !escaped {
+		if n > len(d.data)-d.pos {
+			return nil, d.error("string of %d bytes, %d left", n, len(d.data)-d.pos)
+		}
+		s = slices.Clone(d.data[d.pos : d.pos+n])
+		d.pos += n
+	} else {
+		if n > len(d.data)-d.pos {
+			return nil, d.error("string of %d bytes, %d left", n, len(d.data)-d.pos)
+		}
+		s = make([]byte, 0, n)
+		for len(s) < n {
+			if d.pos >= len(d.data) {
+				return nil, d.error("unexpected end of input in string")
+			}
+			c := d.data[d.pos]
+			if c == '\\' {
+				if d.pos+2 >= len(d.data) {
+					return nil, d.error("unexpected end of input in string")
+				}
+				b, err := strconv.ParseUint(string(d.data[d.pos+1:d.pos+3]), 16, 8)
+				if err != nil {
+					return nil, d.error("invalid escape in string")
+				}
+				c = byte(b)
+				d.pos += 2
+			}
+			s = append(s, c)
+			d.pos++
+		}
+	}
+	return s, d.expect('"')
+}
+
+// name reads a quoted class name.
+func (d *decoder) name() (string, error) {
+	start := d.pos
+	s, err := d.str(false)
+	if err != nil {
+		return "", err
+	}
+	if !validClassName(s) {
+		d.pos = start
+		return "", d.error("invalid class name %q", s)
+	}
+	return string(s), nil
+}
+
+// validClassName reports whether s is a php class name, possibly namespaced.
+func validClassName(s []byte) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case c == '_' || c >= 0x80 || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' || c == '\\':
+			if i == 0 {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func (d *decoder) allowed(class string) bool {
+	return d.config.AllowedClasses == nil || slices.Contains(d.config.AllowedClasses, class)
+}
+
+// count reads an element count, checked against the input left: an element takes at least 4 bytes.
+func (d *decoder) count() (int, error) {
+	start := d.pos
+	n, err := d.uint(':')
+	if err != nil {
+		return 0, err
+	}
+	if n > (len(d.data)-d.pos)/4 {
+		d.pos = start
+		return 0, d.error("%d elements, the input is too short for them", n)
+	}
+	return n, nil
+}
+
+// elements reads `{key;value...}` into a.
+func (d *decoder) elements(a *phparray.Array, n int) error {
+	if d.config.MaxDepth > 0 && d.depth >= d.config.MaxDepth {
+		return d.error("maximum depth of %d exceeded", d.config.MaxDepth)
+	}
+	d.depth++
+	defer func() { d.depth-- }()
+
+	if err := d.expect('{'); err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		k, err := d.key()
+		if err != nil {
+			return err
+		}
+		v, err := d.value()
+		if err != nil {
+			return err
+		}
+		a.Set(k, v)
+	}
+	return d.expect('}')
+}
+
+// key reads an array key, an integer or a string.
+func (d *decoder) key() (phparray.Key, error) {
+	if d.pos+1 >= len(d.data) {
+		return phparray.Key{}, d.error("unexpected end of input")
+	}
+	switch code := d.data[d.pos]; code {
+	case 'i':
+		d.pos++
+		if err := d.expect(':'); err != nil {
+			return phparray.Key{}, err
+		}
+		n, err := d.int(';')
+		return phparray.IntKey(n), err
+	case 's', 'S':
+		d.pos++
+		if err := d.expect(':'); err != nil {
+			return phparray.Key{}, err
+		}
+		s, err := d.str(code == 'S')
+		if err != nil {
+			return phparray.Key{}, err
+		}
+		return phparray.StrKey(string(s)), d.expect(';')
+	default:
+		return phparray.Key{}, d.error("unexpected %q, want an integer or a string key", code)
+	}
+}
+
+func (d *decoder) array(slot int) (*phparray.Array, error) {
+	d.pos++
+	if err := d.expect(':'); err != nil {
+		return nil, err
+	}
+	n, err := d.count()
+	if err != nil {
+		return nil, err
+	}
+
+	a := phparray.New()
+	d.slots[slot] = a
+	return a, d.elements(a, n)
+}
+
+func (d *decoder) object(slot int) (*Object, error) {
+	d.pos++
+	if err := d.expect(':'); err != nil {
+		return nil, err
+	}
+	class, err := d.name()
+	if err != nil {
+		return nil, err
+	}
+	if err := d.expect(':'); err != nil {
+		return nil, err
+	}
+	n, err := d.count()
+	if err != nil {
+		return nil, err
+	}
+
+	o := NewObject(class)
+	if !d.allowed(class) {
+		o.Class = IncompleteClass
+		o.Props.SetString(IncompleteClassName, []byte(class))
+	}
+	d.slots[slot] = o
+	return o, d.elements(o.Props, n)
+}
+
+func (d *decoder) custom() (any, error) {
+	d.pos++
+	if err := d.expect(':'); err != nil {
+		return nil, err
+	}
+	class, err := d.name()
+	if err != nil {
+		return nil, err
+	}
+	if err := d.expect(':'); err != nil {
+		return nil, err
+	}
+	n, err := d.uint(':')
+	if err != nil {
+		return nil, err
+	}
+	if err := d.expect('{'); err != nil {
+		return nil, err
+	}
+	if n > len(d.data)-d.pos {
+		return nil, d.error("data of %d bytes, %d left", n, len(d.data)-d.pos)
+	}
+	data := slices.Clone(d.data[d.pos : d.pos+n])
+	d.pos += n
+	if err := d.expect('}'); err != nil {
+		return nil, err
+	}
+
+	if !d.allowed(class) {
+		// the data can only be read by the class
+		o := NewObject(IncompleteClass)
+		o.Props.SetString(IncompleteClassName, []byte(class))
+		return o, nil
+	}
+	return &Custom{Class: class, Data: data}, nil
+}
+
+func (d *decoder) enum() (Enum, error) {
+	d.pos++
+	if err := d.expect(':'); err != nil {
+		return Enum{}, err
+	}
+	start := d.pos
+	s, err := d.str(false)
+	if err != nil {
+		return Enum{}, err
+	}
+	if err := d.expect(';'); err != nil {
+		return Enum{}, err
+	}
+
+	for i, c := range s {
+		if c == ':' {
+			class, name := s[:i], s[i+1:]
+			if !validClassName(class) || !validClassName(name) {
+				break
+			}
+			if !d.allowed(string(class)) {
+				d.pos = start
+				return Enum{}, d.error("enum %s is not an allowed class", class)
+			}
+			return Enum{Class: string(class), Case: string(name)}, nil
+		}
+	}
+	d.pos = start
+	return Enum{}, d.error("invalid enum case %q", s)
+}
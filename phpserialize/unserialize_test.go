@@ -0,0 +1,216 @@
+package phpserialize
+
+import (
+	"errors"
+	"github.com/xycczZ/php_pack/phparray"
+	"math"
+	"testing"
+)
+
+func TestUnmarshal(t *testing.T) {
+	cases := []struct {
+		Data string
+		// Expected is the value serialized again, php's serialize(unserialize(Data))
+		Expected string
+	}{
+		{`N;`, `N;`},
+		{`b:1;`, `b:1;`},
+		{`i:-42;`, `i:-42;`},
+		{`i:+5;`, `i:5;`},
+		{`d:0.1;`, `d:0.1;`},
+		{`d:-.5e+3;`, `d:-500;`},
+		{`d:1.;`, `d:1;`},
+		{`d:1e400;`, `d:INF;`},
+		{`d:-INF;`, `d:-INF;`},
+		{`d:NAN;`, `d:NAN;`},
+		{`s:6:"héllo";`, `s:6:"héllo";`},
+		{`s:3:"a"b";`, `s:3:"a"b";`},
+		{`S:3:"a\62c";`, `s:3:"abc";`},
+		{`a:0:{}`, `a:0:{}`},
+		{`a:2:{i:5;s:1:"x";s:1:"7";a:1:{s:1:"k";b:0;}}`, `a:2:{i:5;s:1:"x";i:7;a:1:{s:1:"k";b:0;}}`},
+		{"O:3:\"Foo\":2:{s:1:\"a\";i:1;s:6:\"\x00Foo\x00c\";d:2.5;}", "O:3:\"Foo\":2:{s:1:\"a\";i:1;s:6:\"\x00Foo\x00c\";d:2.5;}"},
+		{`O:9:"App\Model":1:{i:0;i:1;}`, `O:9:"App\Model":1:{i:0;i:1;}`},
+		{`C:11:"ArrayObject":21:{x:i:0;a:0:{};m:a:0:{}}`, `C:11:"ArrayObject":21:{x:i:0;a:0:{};m:a:0:{}}`},
+		{`E:11:"Suit:Hearts";`, `E:11:"Suit:Hearts";`},
+		{`a:2:{i:0;O:8:"stdClass":0:{}i:1;r:2;}`, `a:2:{i:0;O:8:"stdClass":0:{}i:1;r:2;}`},
+		{`a:2:{i:0;i:1;i:1;R:2;}`, `a:2:{i:0;i:1;i:1;i:1;}`},
+	}
+
+	for _, c := range cases {
+		v, err := Unmarshal([]byte(c.Data))
+		if err != nil {
+			t.Errorf("unmarshal %q failed: %v\n", c.Data, err)
+			continue
+		}
+		actual, err := Marshal(v)
+		if err != nil {
+			t.Errorf("marshal %q failed: %v\n", c.Data, err)
+			continue
+		}
+		if string(actual) != c.Expected {
+			t.Errorf("unmarshal %q error, expected: %q, actual: %q\n", c.Data, c.Expected, actual)
+		}
+	}
+}
+
+func TestUnmarshalTypes(t *testing.T) {
+	v, err := Unmarshal([]byte(`a:4:{i:0;i:1;i:1;d:1.5;i:2;s:1:"x";i:3;O:3:"Foo":1:{s:4:"` + "\x00*\x00p" + `";b:1;}}`))
+	if err != nil {
+		t.Errorf("unmarshal failed: %v\n", err)
+		return
+	}
+	a, ok := v.(*phparray.Array)
+	if !ok || a.Len() != 4 {
+		t.Errorf("unmarshal error, expected: array(4), actual: %#v\n", v)
+		return
+	}
+
+	values := a.Values()
+	if n, ok := values[0].(int64); !ok || n != 1 {
+		t.Errorf("int error, expected: int64 1, actual: %#v\n", values[0])
+	}
+	if f, ok := values[1].(float64); !ok || f != 1.5 {
+		t.Errorf("float error, expected: float64 1.5, actual: %#v\n", values[1])
+	}
+	if s, ok := values[2].([]byte); !ok || string(s) != "x" {
+		t.Errorf("string error, expected: []byte x, actual: %#v\n", values[2])
+	}
+	o, ok := values[3].(*Object)
+	if !ok || o.Class != "Foo" {
+		t.Errorf("object error, expected: *Object Foo, actual: %#v\n", values[3])
+		return
+	}
+	if p, ok := o.Get("p"); !ok || p != true {
+		t.Errorf("property error, expected: true, actual: %v %v\n", p, ok)
+	}
+	if _, ok := o.Get("q"); ok {
+		t.Errorf("property error, expected: no property q\n")
+	}
+}
+
+func TestUnmarshalReferences(t *testing.T) {
+	// $a = []; $a[0] = &$a; serialize($a);
+	v, err := Unmarshal([]byte(`a:1:{i:0;a:1:{i:0;R:2;}}`))
+	if err != nil {
+		t.Errorf("unmarshal failed: %v\n", err)
+		return
+	}
+	inner, _ := v.(*phparray.Array).Get(phparray.IntKey(0))
+	self, _ := inner.(*phparray.Array).Get(phparray.IntKey(0))
+	if self != inner {
+		t.Errorf("reference error, expected: the inner array refers to itself\n")
+	}
+
+	v, err = Unmarshal([]byte(`a:2:{i:0;O:8:"stdClass":0:{}i:1;r:2;}`))
+	if err != nil {
+		t.Errorf("unmarshal failed: %v\n", err)
+		return
+	}
+	values := v.(*phparray.Array).Values()
+	if values[0] != values[1] {
+		t.Errorf("reference error, expected: one shared object, actual: %p %p\n", values[0], values[1])
+	}
+}
+
+func TestUnmarshalConfig(t *testing.T) {
+	data := []byte(`a:2:{i:0;O:3:"Foo":1:{s:1:"a";i:1;}i:1;C:3:"Bar":1:{x}}`)
+	v, err := UnmarshalConfig(data, Config{AllowedClasses: []string{"Foo"}})
+	if err != nil {
+		t.Errorf("unmarshal failed: %v\n", err)
+		return
+	}
+	actual, _ := Marshal(v)
+	if string(actual) != `a:2:{i:0;O:3:"Foo":1:{s:1:"a";i:1;}i:1;O:3:"Bar":0:{}}` {
+		t.Errorf("allowed classes error, actual: %q\n", actual)
+	}
+
+	v, err = UnmarshalConfig(data, Config{AllowedClasses: []string{}})
+	if err != nil {
+		t.Errorf("unmarshal failed: %v\n", err)
+		return
+	}
+	o, _ := v.(*phparray.Array).Values()[0].(*Object)
+	if o == nil || o.Class != IncompleteClass {
+		t.Errorf("allowed classes error, expected: %s, actual: %#v\n", IncompleteClass, o)
+	}
+
+	cases := []struct {
+		Data   string
+		Config Config
+		Err    string
+	}{
+		{`a:1:{i:0;a:1:{i:0;a:0:{}}}`, Config{MaxDepth: 2}, "phpserialize: error at offset 22 of 26 bytes: maximum depth of 2 exceeded"},
+		{`a:1:{i:0;a:1:{i:0;a:0:{}}}`, Config{MaxElements: 2}, "phpserialize: error at offset 18 of 26 bytes: more than 2 values"},
+		{`E:11:"Suit:Hearts";`, Config{AllowedClasses: []string{"Foo"}}, "phpserialize: error at offset 2 of 19 bytes: enum Suit is not an allowed class"},
+	}
+	for _, c := range cases {
+		_, err := UnmarshalConfig([]byte(c.Data), c.Config)
+		if err == nil || err.Error() != c.Err {
+			t.Errorf("unmarshal %q error, expected: %s, actual: %v\n", c.Data, c.Err, err)
+		}
+	}
+
+	if _, err := UnmarshalConfig([]byte(`a:1:{i:0;a:1:{i:0;a:0:{}}}`), Config{MaxDepth: -1}); err != nil {
+		t.Errorf("unmarshal without depth limit failed: %v\n", err)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	cases := []struct {
+		Data   string
+		Offset int
+		Msg    string
+	}{
+		{``, 0, "unexpected end of input"},
+		{`N`, 0, "unexpected end of input"},
+		{`i:1;x`, 4, "extra data after the value"},
+		{`x:1;`, 0, "unexpected 'x'"},
+		{`b:2;`, 2, "bool must be 0 or 1"},
+		{`i:1.5;`, 3, "unexpected '.', want ';'"},
+		{`i:9223372036854775808;`, 2, "integer 9223372036854775808 out of range"},
+		{`i:;`, 2, "invalid integer"},
+		{`d:.;`, 2, "invalid float"},
+		{`d:1e;`, 4, "invalid float exponent"},
+		{`d:0x1A;`, 3, "unexpected 'x', want ';'"},
+		{`s:9:"abc";`, 5, "string of 9 bytes, 5 left"},
+		{`s:-1:"";`, 2, "invalid length"},
+		{`s:1:"ab";`, 6, "unexpected 'b', want '\"'"},
+		{`S:1:"\zz";`, 5, "invalid escape in string"},
+		{`a:1000000:{}`, 2, "1000000 elements, the input is too short for them"},
+		{`a:1:{d:1;i:1;}`, 5, "unexpected 'd', want an integer or a string key"},
+		{`a:1:{i:0;i:1;`, 13, "unexpected end of input, want '}'"},
+		{`O:3:"1ab":0:{}`, 2, "invalid class name \"1ab\""},
+		{`C:3:"Foo":9:{x}`, 13, "data of 9 bytes, 2 left"},
+		{`E:4:"Suit";`, 2, "invalid enum case \"Suit\""},
+		{`r:1;`, 2, "reference to value 1 of 0"},
+		{`a:1:{i:0;R:3;}`, 11, "reference to value 3 of 1"},
+	}
+
+	for _, c := range cases {
+		_, err := Unmarshal([]byte(c.Data))
+		var se *SyntaxError
+		if !errors.As(err, &se) || se.Offset != c.Offset || se.Len != len(c.Data) || se.Msg != c.Msg {
+			t.Errorf("unmarshal %q error, expected: %d %s, actual: %v\n", c.Data, c.Offset, c.Msg, err)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	values := []any{int64(math.MinInt64), math.MaxFloat64, 5e-324, []byte{0, 1, 0xff}}
+	for _, v := range values {
+		data, err := Marshal(v)
+		if err != nil {
+			t.Errorf("marshal %v failed: %v\n", v, err)
+			continue
+		}
+		actual, err := Unmarshal(data)
+		if err != nil {
+			t.Errorf("unmarshal %q failed: %v\n", data, err)
+			continue
+		}
+		again, _ := Marshal(actual)
+		if string(again) != string(data) {
+			t.Errorf("round trip %v error, expected: %q, actual: %q\n", v, data, again)
+		}
+	}
+}
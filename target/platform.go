@@ -0,0 +1,105 @@
+package target
+
+import (
+	"encoding/binary"
+	"strconv"
+)
+
+// Platform is the machine a php binary runs on, as far as pack and unpack can tell:
+// the byte order of the machine dependent codes (s S i I l L q Q f d),
+// sizeof(int) for i and I, and whether php is a 64-bit build.
+// The zero Platform stands for the Go host.
+type Platform struct {
+	BigEndian bool
+	// IntSize is sizeof(int): 2, 4 or 8
+	IntSize int
+	// Is64Bit is set for a 64-bit php, where zend_long has 64 bits.
+	// A 32-bit php has no q, Q, J and P codes and unpacks integers as 32-bit zend_long.
+	Is64Bit bool
+}
+
+var (
+	AMD64   = Platform{BigEndian: false, IntSize: 4, Is64Bit: true}
+	ARM64   = Platform{BigEndian: false, IntSize: 4, Is64Bit: true}
+	I386    = Platform{BigEndian: false, IntSize: 4, Is64Bit: false}
+	ARM     = Platform{BigEndian: false, IntSize: 4, Is64Bit: false}
+	PPC64LE = Platform{BigEndian: false, IntSize: 4, Is64Bit: true}
+	S390X   = Platform{BigEndian: true, IntSize: 4, Is64Bit: true}
+	PPC64   = Platform{BigEndian: true, IntSize: 4, Is64Bit: true}
+	MIPS    = Platform{BigEndian: true, IntSize: 4, Is64Bit: false}
+)
+
+// Host returns the platform of the Go host, assuming a php built for the same architecture.
+func Host() Platform {
+	return Platform{
+		BigEndian: binary.NativeEndian.Uint16([]byte{0, 1}) == 1,
+		IntSize:   4,
+		Is64Bit:   strconv.IntSize == 64,
+	}
+}
+
+// IsZero reports whether p is the zero Platform, i.e. the Go host.
+func (p Platform) IsZero() bool {
+	return p == Platform{}
+}
+
+// OrHost returns p, or the Go host platform when p is zero.
+func (p Platform) OrHost() Platform {
+	if p.IsZero() {
+		return Host()
+	}
+	return p
+}
+
+// HasCode reports whether code is available on p, a 32-bit php has no q, Q, J and P.
+func (p Platform) HasCode(code byte) bool {
+	switch code {
+	case 'q', 'Q', 'J', 'P':
+		return p.OrHost().Is64Bit
+	}
+	return true
+}
+
+// ValidIntSize reports whether the IntSize of p is one i and I can use: 2, 4 or 8.
+// A Platform with another IntSize, like a partly set one, can not compile formats with i or I.
+func (p Platform) ValidIntSize() bool {
+	switch p.OrHost().IntSize {
+	case 2, 4, 8:
+		return true
+	}
+	return false
+}
+
+// ByteOrder returns the byte order of the machine dependent codes.
+func (p Platform) ByteOrder() binary.ByteOrder {
+	if p.OrHost().BigEndian {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
+
+// OrderOf returns the byte order of a numeric code on p:
+// fixed for n v N V J P g G e E, the byte order of p for the machine dependent codes.
+func (p Platform) OrderOf(code byte) binary.ByteOrder {
+	switch code {
+	case 'n', 'N', 'J', 'G', 'E':
+		return binary.BigEndian
+	case 'v', 'V', 'P', 'g', 'e':
+		return binary.LittleEndian
+	}
+	return p.ByteOrder()
+}
+
+func (p Platform) String() string {
+	p = p.OrHost()
+	s := "little-endian"
+	if p.BigEndian {
+		s = "big-endian"
+	}
+	if p.Is64Bit {
+		s += " 64-bit"
+	} else {
+		s += " 32-bit"
+	}
+	return s + " int" + strconv.Itoa(p.IntSize*8)
+}
@@ -0,0 +1,43 @@
+package target
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestPlatform(t *testing.T) {
+	cases := []struct {
+		Platform Platform
+		Order    binary.ByteOrder
+		Codes64  bool
+		Str      string
+	}{
+		{AMD64, binary.LittleEndian, true, "little-endian 64-bit int32"},
+		{I386, binary.LittleEndian, false, "little-endian 32-bit int32"},
+		{S390X, binary.BigEndian, true, "big-endian 64-bit int32"},
+		{MIPS, binary.BigEndian, false, "big-endian 32-bit int32"},
+	}
+
+	for _, c := range cases {
+		if c.Platform.ByteOrder() != c.Order || c.Platform.HasCode('J') != c.Codes64 || c.Platform.String() != c.Str {
+			t.Errorf("platform %s error, expected: %v %v %s\n", c.Platform, c.Order, c.Codes64, c.Str)
+		}
+		if !c.Platform.HasCode('N') {
+			t.Errorf("platform %s should have N\n", c.Platform)
+		}
+	}
+
+	for size, valid := range map[int]bool{0: false, 2: true, 3: false, 4: true, 8: true} {
+		p := Platform{BigEndian: true, IntSize: size}
+		if p.ValidIntSize() != valid {
+			t.Errorf("int size %d error, expected: %v\n", size, valid)
+		}
+	}
+	if !(Platform{}).ValidIntSize() {
+		t.Errorf("the host int size should be valid\n")
+	}
+
+	if !(Platform{}).IsZero() || (Platform{}).OrHost() != Host() || Host().IntSize != 4 {
+		t.Errorf("zero platform should be the host, host: %s\n", Host())
+	}
+}
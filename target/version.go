@@ -0,0 +1,138 @@
+// Package target describes the php release pack and unpack emulate.
+package target
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a php release. The zero Version stands for the latest behavior.
+type Version struct {
+	Major, Minor, Patch int
+}
+
+var (
+	PHP54 = Version{5, 4, 0}
+	PHP55 = Version{5, 5, 0}
+	PHP56 = Version{5, 6, 0}
+	PHP70 = Version{7, 0, 0}
+	PHP71 = Version{7, 1, 0}
+	PHP72 = Version{7, 2, 0}
+	PHP73 = Version{7, 3, 0}
+	PHP74 = Version{7, 4, 0}
+	PHP80 = Version{8, 0, 0}
+	PHP81 = Version{8, 1, 0}
+	PHP82 = Version{8, 2, 0}
+	PHP83 = Version{8, 3, 0}
+	PHP84 = Version{8, 4, 0}
+
+	// Latest is the release the default behavior follows.
+	Latest = PHP84
+)
+
+// ParseVersion parses versions like "7.4", "8.1.2" or "PHP 8.0".
+func ParseVersion(s string) (Version, error) {
+	str := strings.TrimSpace(s)
+	str = strings.TrimPrefix(strings.TrimPrefix(str, "PHP"), "php")
+	str = strings.TrimSpace(str)
+
+	parts := strings.Split(str, ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return Version{}, fmt.Errorf("invalid php version %q", s)
+	}
+
+	var nums [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("invalid php version %q", s)
+		}
+		nums[i] = n
+	}
+	return Version{nums[0], nums[1], nums[2]}, nil
+}
+
+func (v Version) String() string {
+	if v.IsZero() {
+		return Latest.String()
+	}
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// IsZero reports whether v is the zero Version, i.e. the latest behavior.
+func (v Version) IsZero() bool {
+	return v == Version{}
+}
+
+// Compare returns -1, 0 or 1 when v is older, the same or newer than o.
+func (v Version) Compare(o Version) int {
+	v, o = v.orLatest(), o.orLatest()
+	for _, d := range [3]int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d < 0 {
+			return -1
+		}
+		if d > 0 {
+			return 1
+		}
+	}
+	return 0
+}
+
+// AtLeast reports whether v is o or newer.
+func (v Version) AtLeast(o Version) bool {
+	return v.Compare(o) >= 0
+}
+
+func (v Version) orLatest() Version {
+	if v.IsZero() {
+		return Latest
+	}
+	return v
+}
+
+// HasZ reports whether the Z code exists (php 5.5).
+// Before 5.5, unpack's a code strips trailing NUL bytes and A only trailing spaces.
+func (v Version) HasZ() bool {
+	return v.AtLeast(PHP55)
+}
+
+// Has64BitCodes reports whether the q, Q, J and P codes exist (php 5.6.3).
+func (v Version) Has64BitCodes() bool {
+	return v.AtLeast(Version{5, 6, 3})
+}
+
+// HasFloatOrderCodes reports whether the e, E, g and G codes exist (php 7.0.15 and 7.1.1).
+func (v Version) HasFloatOrderCodes() bool {
+	if v.Major == 7 && v.Minor == 0 {
+		return v.Patch >= 15
+	}
+	return v.AtLeast(Version{7, 1, 1})
+}
+
+// HasUnpackOffset reports whether unpack takes an offset (php 7.1).
+func (v Version) HasUnpackOffset() bool {
+	return v.AtLeast(PHP71)
+}
+
+// ThrowsValueError reports whether errors in the format or the arguments throw (php 8.0).
+// Older releases emit a warning and return false.
+func (v Version) ThrowsValueError() bool {
+	return v.AtLeast(PHP80)
+}
+
+// HasCode reports whether code is a pack/unpack format code in v.
+func (v Version) HasCode(code byte) bool {
+	switch code {
+	case 'a', 'A', 'h', 'H', 'c', 'C', 's', 'S', 'n', 'v', 'i', 'I',
+		'l', 'L', 'N', 'V', 'f', 'd', 'x', 'X', '@':
+		return true
+	case 'Z':
+		return v.HasZ()
+	case 'q', 'Q', 'J', 'P':
+		return v.Has64BitCodes()
+	case 'e', 'E', 'g', 'G':
+		return v.HasFloatOrderCodes()
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package target
+
+import "testing"
+
+func TestParseVersion(t *testing.T) {
+	cases := []struct {
+		Str     string
+		Version Version
+		Err     bool
+	}{
+		{"7.4", PHP74, false},
+		{"8.1.2", Version{8, 1, 2}, false},
+		{"PHP 8.0", PHP80, false},
+		{" php7.0.15 ", Version{7, 0, 15}, false},
+		{"8", Version{}, true},
+		{"8.x", Version{}, true},
+		{"8.1.2.3", Version{}, true},
+	}
+
+	for i := range cases {
+		v, err := ParseVersion(cases[i].Str)
+		if (err != nil) != cases[i].Err || v != cases[i].Version {
+			t.Errorf("parse %q error, expected: %v %v, actual: %v %v\n", cases[i].Str, cases[i].Version, cases[i].Err, v, err)
+		}
+	}
+}
+
+func TestFeatures(t *testing.T) {
+	cases := []struct {
+		Version    Version
+		Z          bool
+		Codes64    bool
+		FloatOrder bool
+		Offset     bool
+		ValueError bool
+	}{
+		{PHP54, false, false, false, false, false},
+		{PHP55, true, false, false, false, false},
+		{Version{5, 6, 2}, true, false, false, false, false},
+		{Version{5, 6, 3}, true, true, false, false, false},
+		{Version{7, 0, 14}, true, true, false, false, false},
+		{Version{7, 0, 15}, true, true, true, false, false},
+		{PHP71, true, true, false, true, false},
+		{Version{7, 1, 1}, true, true, true, true, false},
+		{PHP74, true, true, true, true, false},
+		{PHP80, true, true, true, true, true},
+		{PHP84, true, true, true, true, true},
+		{Version{}, true, true, true, true, true},
+	}
+
+	for _, c := range cases {
+		v := c.Version
+		if v.HasZ() != c.Z || v.Has64BitCodes() != c.Codes64 || v.HasFloatOrderCodes() != c.FloatOrder ||
+			v.HasUnpackOffset() != c.Offset || v.ThrowsValueError() != c.ValueError {
+			t.Errorf("features of %s error, expected: %+v\n", v, c)
+		}
+		if v.HasCode('Z') != c.Z || v.HasCode('J') != c.Codes64 || v.HasCode('e') != c.FloatOrder || !v.HasCode('N') || v.HasCode('y') {
+			t.Errorf("codes of %s error\n", v)
+		}
+	}
+
+	if PHP74.Compare(PHP80) != -1 || PHP80.Compare(PHP74) != 1 || (Version{}).Compare(Latest) != 0 {
+		t.Errorf("compare error\n")
+	}
+}
@@ -0,0 +1,27 @@
+<?php
+// Writes vectors.json: the values of handwritten.json with the output of the installed
+// igbinary extension, every vector records the extension version in "igbinary".
+
+class Foo { public $a = 1; protected $b = 2; private $c = 3; }
+class Bar {}
+class Ser implements Serializable {
+    public function serialize() { return 'hello'; }
+    public function unserialize($data) {}
+}
+
+$version = phpversion('igbinary');
+if ($version === false) {
+    fwrite(STDERR, "gen.php: the igbinary extension is not loaded\n");
+    exit(1);
+}
+
+$vectors = json_decode(file_get_contents(__DIR__ . '/handwritten.json'), true);
+foreach ($vectors as &$vector) {
+    $value = (function () use ($vector) { return eval($vector['php']); })();
+    $vector['hex'] = bin2hex(igbinary_serialize($value));
+    $vector['serialized'] = serialize($value);
+    $vector['igbinary'] = $version;
+}
+unset($vector);
+
+echo json_encode($vectors, JSON_PRETTY_PRINT | JSON_UNESCAPED_SLASHES), "\n";
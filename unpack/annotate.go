@@ -0,0 +1,177 @@
+package unpack
+
+import (
+	"fmt"
+	"github.com/xycczZ/php_pack/internal/diag"
+	"github.com/xycczZ/php_pack/phparray"
+	"github.com/xycczZ/php_pack/phpconv"
+	"strconv"
+	"strings"
+)
+
+// span is the input one value, or one x, X or @ field, covers.
+type span struct {
+	// field is the index of the field in the format
+	field int
+	code  byte
+	// key is the result key, empty for x, X and @
+	key string
+	// offset and size are the bytes covered,
+	// X and a backward @ cover nothing and start where they move to
+	offset, size int
+	value        any
+	// need is the size the field needed when the input ran short, size is what was left
+	need int
+	// warning is a warning of the field with the code, the other members are unset
+	warning string
+}
+
+// addSpan records the bytes of one repetition, the x repetitions of a field are merged.
+func addSpan(spans *[]span, result *phparray.Array, s span) {
+	if s.size < 0 {
+		s.offset += s.size
+		s.size = 0
+	}
+
+	switch s.code {
+	case 'x', 'X', '@':
+		s.key = ""
+	default:
+		s.value, _ = result.GetString(s.key)
+	}
+
+	n := len(*spans)
+	if s.code == 'x' && n > 0 {
+		last := &(*spans)[n-1]
+		if last.field == s.field && last.offset+last.size == s.offset {
+			last.size += s.size
+			return
+		}
+	}
+	// a field warns before its span is added, show the warning after it
+	if n > 0 && (*spans)[n-1].warning != "" && (*spans)[n-1].code == s.code {
+		*spans = append((*spans)[:n-1], s, (*spans)[n-1])
+		return
+	}
+	*spans = append(*spans, s)
+}
+
+// Annotate unpacks data with format and returns a hex dump of data annotated with the field,
+// the key and the value of every byte range, see Format.Annotate.
+func Annotate(format string, data []byte) (string, error) {
+	f, err := Compile(format)
+	if err != nil {
+		return "", err
+	}
+	return f.Annotate(data, 0)
+}
+
+// Annotate returns a hex dump of data, 16 bytes a line, annotated with what unpacking it
+// from offset reads: the field, the key and the value of every byte range, the bytes x and @ skip,
+// where X and @ move to, warnings, and where the input runs short.
+// Bytes before offset and after the last field are marked as well.
+// Running out of input is shown in the dump rather than returned as an error.
+func (f *Format) Annotate(data []byte, offset int) (string, error) {
+	var spans []span
+	w := &diag.Warner{Config: &diag.Config{
+		Version:  f.config.Version,
+		Platform: f.config.Platform,
+		OnWarning: func(warning Warning) {
+			spans = append(spans, span{code: warning.Code, warning: warning.String()})
+		},
+	}}
+	_, err := f.unpack(data, offset, false, w, &spans)
+
+	if err != nil && !(len(spans) > 0 && spans[len(spans)-1].need > 0) {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString("offset    bytes                                            field\n")
+	if offset > 0 {
+		writeLines(&sb, data, 0, offset, "(before offset)")
+	}
+
+	end := offset
+	for _, s := range spans {
+		if s.warning != "" {
+			fmt.Fprintf(&sb, "%s  warning: %s\n", strings.Repeat(" ", 57), s.warning)
+			continue
+		}
+		writeLines(&sb, data, s.offset, s.offset+s.size, f.label(s))
+		end = max(end, s.offset+s.size)
+	}
+	if end < len(data) {
+		writeLines(&sb, data, end, len(data), "(unread)")
+	}
+	return sb.String(), nil
+}
+
+// label describes a span, e.g. `n2 int1 = 65` or `x2 skipped`.
+func (f *Format) label(s span) string {
+	fd := f.fields[s.field]
+	count := fd.repetitions
+	switch fd.code {
+	case 'a', 'A', 'Z', 'h', 'H':
+		count = fd.argb
+	}
+
+	var sb strings.Builder
+	sb.WriteByte(fd.code)
+	switch {
+	case count < 0:
+		sb.WriteByte('*')
+	case count != 1 || fd.code == '@':
+		sb.WriteString(strconv.Itoa(count))
+	}
+
+	switch {
+	case s.need > 0:
+		fmt.Fprintf(&sb, " <-- input ends, needs %d bytes, %d left", s.need, s.size)
+	case fd.code == 'x' || (fd.code == '@' && s.size > 0):
+		sb.WriteString(" skipped")
+	case fd.code == 'X':
+		sb.WriteString(" moves back")
+	case fd.code == '@':
+		sb.WriteString(" sets the position")
+	default:
+		if phparray.StrKey(s.key).IsInt() {
+			fmt.Fprintf(&sb, " [%s]", s.key)
+		} else {
+			fmt.Fprintf(&sb, " %s", s.key)
+		}
+		sb.WriteString(" = ")
+		sb.WriteString(formatValue(s.value))
+	}
+	return sb.String()
+}
+
+func formatValue(v any) string {
+	switch x := v.(type) {
+	case []byte:
+		return strconv.Quote(string(x))
+	case float64:
+		return phpconv.FormatFloat(x, phpconv.SerializePrecision)
+	}
+	return fmt.Sprint(v)
+}
+
+// writeLines writes data[from:to] 16 bytes a line, the label goes on the first line.
+// An empty range still gets a line for its label.
+func writeLines(sb *strings.Builder, data []byte, from, to int, label string) {
+	for pos := from; pos < to || pos == from; pos += 16 {
+		lineEnd := min(to, pos+16)
+		var hex strings.Builder
+		for i := pos; i < lineEnd; i++ {
+			if i > pos {
+				hex.WriteByte(' ')
+			}
+			fmt.Fprintf(&hex, "%02x", data[i])
+		}
+
+		line := fmt.Sprintf("%08x  %-47s  %s", pos, hex.String(), label)
+		sb.WriteString(strings.TrimRight(line, " "))
+		sb.WriteByte('\n')
+		label = ""
+	}
+}
@@ -0,0 +1,54 @@
+package unpack
+
+import (
+	"testing"
+)
+
+func TestAnnotate(t *testing.T) {
+	data := []byte("\x00\x00\x00\x05\x00\x00alice           \x01\x02\x03\x04\x05\x06\x07\x08\x09\x0a\x0bAB")
+	expected := `offset    bytes                                            field
+00000000  00 00 00 05                                      N len = 5
+00000004  00 00                                            x2 skipped
+00000006  61 6c 69 63 65 20 20 20 20 20 20 20 20 20 20 20  A16 name = "alice"
+00000016  01                                               C2 [1] = 1
+00000017  02                                               C2 [2] = 2
+00000018  03 04 05 06 07 08                                @30 skipped
+0000001c                                                   X2 moves back
+0000001c  07 08                                            n2 n1 = 1800
+0000001e  09 0a                                            n2 n2 = 2314
+00000020  0b 41 42                                         N <-- input ends, needs 4 bytes, 3 left
+`
+	s, err := Annotate("Nlen/x2/A16name/C2/@30/X2/n2n/Ncrc", data)
+	if err != nil {
+		t.Errorf("annotate failed: %v\n", err)
+	} else if s != expected {
+		t.Errorf("annotate error, expected:\n%s\nactual:\n%s\n", expected, s)
+	}
+
+	f, err := Compile("C/X3/x/@40/E")
+	if err != nil {
+		t.Errorf("compile failed: %v\n", err)
+		return
+	}
+	data = []byte("abcdef\x3f\xf8\x00\x00\x00\x00\x00\x00\xff\xff")
+	expected = `offset    bytes                                            field
+00000000  61                                               (before offset)
+00000001  62                                               C [1] = 98
+00000001                                                   X3 moves back
+00000001  62                                               x skipped
+00000002                                                   @40 sets the position
+                                                           warning: type @: outside of string
+00000002  63 64 65 66 3f f8 00 00                          E [1] = 6.157926622455476E+170
+0000000a  00 00 00 00 ff ff                                (unread)
+`
+	s, err = f.Annotate(data, 1)
+	if err != nil {
+		t.Errorf("annotate failed: %v\n", err)
+	} else if s != expected {
+		t.Errorf("annotate error, expected:\n%s\nactual:\n%s\n", expected, s)
+	}
+
+	if _, err := f.Annotate(data, 99); err == nil {
+		t.Errorf("annotate outside of the input should fail\n")
+	}
+}
@@ -0,0 +1,98 @@
+package unpack
+
+import (
+	"github.com/xycczZ/php_pack/pack"
+	"testing"
+)
+
+var benchmarks = []struct {
+	Name   string
+	Pack   string
+	Unpack string
+	Args   []any
+}{
+	{"numeric", "nNvVcCsSlLqQJP", "na/Nb/vc/Vd/ce/Cf/sg/Sh/li/Lj/qk/Ql/Jm/Pn", []any{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14}},
+	{"float", "gGeEfd", "ga/Gb/ec/Ed/fe/df", []any{1.5, 2.5, 3.5, 4.5, 5.5, 6.5}},
+	{"string", "a16A16Z16a*", "a16a/A16b/Z16c/a*d", []any{"hello", "world", "php", "pack and unpack"}},
+	{"hex", "H64h64H*", "H64a/h64b/H*c", []any{
+		"0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+		"fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210",
+		"00112233445566778899aabbccddeeff",
+	}},
+	{"repeated", "C32", "C32", []any{
+		1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16,
+		17, 18, 19, 20, 21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31, 32,
+	}},
+}
+
+func BenchmarkPHPUnpack(b *testing.B) {
+	for _, bm := range benchmarks {
+		b.Run(bm.Name, func(b *testing.B) {
+			data, err := pack.PHPPack(bm.Pack, bm.Args...)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := PHPUnpack(NewOption(bm.Unpack, data)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkUnpackArray(b *testing.B) {
+	for _, bm := range benchmarks {
+		b.Run(bm.Name, func(b *testing.B) {
+			data, err := pack.PHPPack(bm.Pack, bm.Args...)
+			if err != nil {
+				b.Fatal(err)
+			}
+			f, err := Compile(bm.Unpack)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := f.UnpackArray(data, 0); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkUnpackSlices(b *testing.B) {
+	args := make([]any, 100)
+	for i := range args {
+		args[i] = i
+	}
+	data, err := pack.PHPPack("N100", args...)
+	if err != nil {
+		b.Fatal(err)
+	}
+	f, err := Compile("N100vals")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("keys", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := f.Unpack(data, 0); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("slices", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := f.UnpackSlices(data, 0); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package unpack
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/xycczZ/php_pack/phparray"
+	"io"
+)
+
+// Decoder reads back-to-back records of one format from a reader.
+// A Decoder is not safe for concurrent use.
+type Decoder struct {
+	// MaxTail is the most bytes a '*' in the last field reads, DefaultMaxTail when 0.
+	// A longer rest of the stream is an error.
+	MaxTail int
+
+	r    *bufio.Reader
+	f    *Format
+	done bool
+}
+
+// DefaultMaxTail is the most bytes a '*' tail reads when Decoder.MaxTail is 0.
+const DefaultMaxTail = 1 << 20
+
+// NewDecoder compiles format and returns a Decoder reading from r.
+//
+// The format must have a fixed size, or use '*' only in its last field:
+// that field then takes the rest of the stream, up to MaxTail bytes, and the record is the last one.
+func NewDecoder(r io.Reader, format string) (*Decoder, error) {
+	return NewDecoderConfig(r, format, Config{})
+}
+
+// NewDecoderConfig is NewDecoder with the warning handling, php version and platform of config.
+func NewDecoderConfig(r io.Reader, format string, config Config) (*Decoder, error) {
+	f, err := CompileConfig(format, config)
+	if err != nil {
+		return nil, err
+	}
+	return f.NewDecoder(r)
+}
+
+// NewDecoder returns a Decoder reading records of f from r.
+func (f *Format) NewDecoder(r io.Reader) (*Decoder, error) {
+	if f.fixed && f.size == 0 {
+		return nil, &FormatError{Msg: fmt.Sprintf("format %q reads no input", f.format)}
+	}
+	if !f.fixed && !f.tail {
+		return nil, &FormatError{Msg: fmt.Sprintf("format %q: only the last field can use '*' in a stream", f.format)}
+	}
+
+	return &Decoder{
+		r: bufio.NewReader(r),
+		f: f,
+	}, nil
+}
+
+// Decode reads and unpacks the next record.
+// It returns io.EOF when the stream ends on a record boundary,
+// and io.ErrUnexpectedEOF when it ends inside a record.
+func (d *Decoder) Decode() (map[string]any, error) {
+	result, err := d.DecodeArray()
+	if err != nil {
+		return nil, err
+	}
+	return result.Map(), nil
+}
+
+// DecodeArray is Decode returning the fields in format order.
+func (d *Decoder) DecodeArray() (*phparray.Array, error) {
+	record, err := d.next()
+	if err != nil {
+		return nil, err
+	}
+	return d.f.UnpackArray(record, 0)
+}
+
+// next reads the bytes of the next record.
+func (d *Decoder) next() ([]byte, error) {
+	if d.done {
+		return nil, io.EOF
+	}
+
+	size := d.f.size
+	if !d.f.fixed {
+		size = d.f.prefix
+	}
+
+	record := make([]byte, size)
+	if _, err := io.ReadFull(d.r, record); err != nil {
+		return nil, err
+	}
+
+	if !d.f.fixed {
+		// a '*' tail takes the rest of the stream
+		limit := d.MaxTail
+		if limit <= 0 {
+			limit = DefaultMaxTail
+		}
+		rest, err := io.ReadAll(io.LimitReader(d.r, int64(limit)+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(rest) > limit {
+			d.done = true
+			return nil, &InputError{Pos: size + limit, Have: len(rest), Msg: fmt.Sprintf("the '*' tail is longer than %d bytes", limit)}
+		}
+		if size == 0 && len(rest) == 0 {
+			return nil, io.EOF
+		}
+		record = append(record, rest...)
+		d.done = true
+	}
+
+	return record, nil
+}
@@ -0,0 +1,119 @@
+package unpack
+
+import (
+	"bytes"
+	"errors"
+	"github.com/xycczZ/php_pack/pack"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func TestDecoder(t *testing.T) {
+	var stream []byte
+	for i := 1; i <= 3; i++ {
+		r, err := pack.PHPPack("nA4", i, "ab")
+		if err != nil {
+			t.Errorf("pack failed: %v\n", err)
+			return
+		}
+		stream = append(stream, r...)
+	}
+
+	// one byte at a time to check records are read across short reads
+	dec, err := NewDecoder(iotest.OneByteReader(bytes.NewReader(stream)), "nid/A4name")
+	if err != nil {
+		t.Errorf("new decoder failed: %v\n", err)
+		return
+	}
+
+	for i := 1; i <= 3; i++ {
+		r, err := dec.Decode()
+		if err != nil {
+			t.Errorf("decode %d failed: %v\n", i, err)
+			return
+		}
+		expected := map[string]any{"id": int64(i), "name": []byte("ab")}
+		if !mapEq(r, expected) {
+			t.Errorf("decode %d error, expected: %v, actual: %v\n", i, expected, r)
+		}
+	}
+
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Errorf("decode should return io.EOF at the end, actual: %v\n", err)
+	}
+
+	dec, err = NewDecoder(bytes.NewReader(stream[:len(stream)-1]), "nid/A4name")
+	if err != nil {
+		t.Errorf("new decoder failed: %v\n", err)
+		return
+	}
+	for i := 1; i <= 2; i++ {
+		if _, err := dec.Decode(); err != nil {
+			t.Errorf("decode %d failed: %v\n", i, err)
+			return
+		}
+	}
+	if _, err := dec.Decode(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("decode should return io.ErrUnexpectedEOF on truncation, actual: %v\n", err)
+	}
+}
+
+func TestDecoderTail(t *testing.T) {
+	bin, err := pack.PHPPack("Na*", 7, "rest of the stream")
+	if err != nil {
+		t.Errorf("pack failed: %v\n", err)
+		return
+	}
+
+	dec, err := NewDecoder(bytes.NewReader(bin), "Nlen/a*data")
+	if err != nil {
+		t.Errorf("new decoder failed: %v\n", err)
+		return
+	}
+
+	r, err := dec.Decode()
+	if err != nil {
+		t.Errorf("decode failed: %v\n", err)
+		return
+	}
+	expected := map[string]any{"len": int64(7), "data": []byte("rest of the stream")}
+	if !mapEq(r, expected) {
+		t.Errorf("decode error, expected: %v, actual: %v\n", expected, r)
+	}
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Errorf("decode should return io.EOF after the tail, actual: %v\n", err)
+	}
+
+	dec, err = NewDecoder(bytes.NewReader(nil), "Nlen/a*data")
+	if err != nil {
+		t.Errorf("new decoder failed: %v\n", err)
+		return
+	}
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Errorf("decode of an empty stream should return io.EOF, actual: %v\n", err)
+	}
+
+	dec, err = NewDecoder(bytes.NewReader(bin), "Nlen/a*data")
+	if err != nil {
+		t.Errorf("new decoder failed: %v\n", err)
+		return
+	}
+	dec.MaxTail = 4
+	if _, err := dec.Decode(); !errors.Is(err, ErrInput) {
+		t.Errorf("decode should fail on a tail longer than MaxTail, actual: %v\n", err)
+	}
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Errorf("decode should return io.EOF after the tail, actual: %v\n", err)
+	}
+
+	if _, err := NewDecoderConfig(bytes.NewReader(bin), "N/X*", Config{Strict: true}); !errors.Is(err, ErrWarning) {
+		t.Errorf("new decoder in strict mode should fail on '*' ignored, actual: %v\n", err)
+	}
+
+	for _, format := range []string{"a*data/N", "C*/n*", "X", "@0"} {
+		if _, err := NewDecoder(bytes.NewReader(bin), format); err == nil {
+			t.Errorf("new decoder should fail on format %s\n", format)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package unpack
+
+import "github.com/xycczZ/php_pack/internal/diag"
+
+// The error types returned by unpack, shared with the pack package.
+type (
+	FormatError   = diag.FormatError
+	ArgumentError = diag.ArgumentError
+	InputError    = diag.InputError
+	// WarningError is returned in place of a warning in strict mode.
+	WarningError = diag.WarningError
+	// Warning is a warning php would emit, see Config.
+	Warning = diag.Warning
+)
+
+var (
+	ErrFormat   = diag.ErrFormat
+	ErrArgument = diag.ErrArgument
+	ErrInput    = diag.ErrInput
+	ErrWarning  = diag.ErrWarning
+)
@@ -0,0 +1,80 @@
+package unpack
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestErrors(t *testing.T) {
+	_, err := PHPUnpack(NewOption("Nlen/ydata", []byte{0, 0, 0, 1}))
+	var fe *FormatError
+	if !errors.As(err, &fe) || !errors.Is(err, ErrFormat) {
+		t.Errorf("unknown code should be a format error, actual: %v\n", err)
+		return
+	}
+	if fe.Code != 'y' || fe.Pos != 5 || err.Error() != "type y: unknown format code" {
+		t.Errorf("format error error, actual: %+v\n", fe)
+	}
+
+	_, err = PHPUnpack(&Option{Format: "nshort/Nlong", Val: []byte{0, 0, 0, 1, 2, 3}, Offset: 1})
+	var ie *InputError
+	if !errors.As(err, &ie) || !errors.Is(err, ErrInput) || errors.Is(err, ErrFormat) {
+		t.Errorf("short input should be an input error, actual: %v\n", err)
+		return
+	}
+	if ie.Code != 'N' || ie.Pos != 3 || ie.Need != 4 || ie.Have != 3 || err.Error() != "type N: not enough input, need 4, have 3" {
+		t.Errorf("input error error, actual: %+v\n", ie)
+	}
+
+	_, err = PHPUnpack(&Option{Format: "n", Val: []byte{0, 0}, Offset: 3})
+	if !errors.As(err, &ie) || ie.Code != 0 || ie.Pos != 3 {
+		t.Errorf("bad offset should be an input error, actual: %v\n", err)
+	}
+}
+
+func TestWarnings(t *testing.T) {
+	option := NewOption("Cfirst/X*/X2/@5", []byte{1, 2})
+	r, err := PHPUnpack(option)
+	if err != nil {
+		t.Errorf("unpack failed: %v\n", err)
+		return
+	}
+	if !mapEq(r, map[string]any{"first": int64(1)}) {
+		t.Errorf("unpack error, actual: %v\n", r)
+	}
+
+	// like php, X silently stops at the start of the input
+	expected := []string{"type X: '*' ignored", "type @: outside of string"}
+	if len(option.Warnings) != len(expected) {
+		t.Errorf("warnings error, expected: %v, actual: %v\n", expected, option.Warnings)
+		return
+	}
+	for i := range expected {
+		if option.Warnings[i].String() != expected[i] {
+			t.Errorf("warning %d error, expected: %s, actual: %s\n", i, expected[i], option.Warnings[i])
+		}
+	}
+
+	option.Strict = true
+	_, err = PHPUnpack(option)
+	var we *WarningError
+	if !errors.As(err, &we) || we.Warning.String() != expected[0] || len(option.Warnings) != 1 {
+		t.Errorf("strict mode should fail on the first warning, actual: %v %v\n", err, option.Warnings)
+	}
+
+	if _, err := CompileConfig("X*", Config{Strict: true}); !errors.Is(err, ErrWarning) {
+		t.Errorf("strict compile should fail on a warning of every call, actual: %v\n", err)
+	}
+
+	var warnings []Warning
+	f, err := CompileConfig("C/@2", Config{OnWarning: func(w Warning) {
+		warnings = append(warnings, w)
+	}})
+	if err != nil {
+		t.Errorf("compile failed: %v\n", err)
+		return
+	}
+	if _, err := f.Unpack([]byte{1}, 0); err != nil || len(warnings) != 1 {
+		t.Errorf("callback error, actual: %v %v\n", err, warnings)
+	}
+}
@@ -0,0 +1,61 @@
+package unpack
+
+import (
+	"github.com/xycczZ/php_pack/internal/layout"
+	"github.com/xycczZ/php_pack/internal/utils"
+)
+
+// Field is one field of a format and the bytes it reads, see Explain.
+type Field = layout.Field
+
+// Explain returns the fields of format with their names, offsets and sizes in the input.
+// A '*' count depends on the input: the size of that field, the offsets from it on
+// and the bytes a later @ skips are -1.
+// @ past the bytes read so far covers the bytes it skips.
+func Explain(format string) ([]Field, error) {
+	f, err := Compile(format)
+	if err != nil {
+		return nil, err
+	}
+	return f.Explain(), nil
+}
+
+// Explain is the package Explain for a compiled format.
+func (f *Format) Explain() []Field {
+	fields := make([]Field, len(f.fields))
+	pos := 0
+	known := true
+	for i, fd := range f.fields {
+		field := Field{Code: fd.code, Name: fd.name, Count: fd.repetitions, Pos: fd.pos}
+		layout.Describe(&field, f.platform)
+
+		switch fd.code {
+		case 'a', 'A', 'Z', 'h', 'H':
+			field.Count = fd.argb
+			field.Offset, field.Size = pos, fd.size
+		case 'X':
+			field.Offset = utils.Max(0, pos-fd.repetitions)
+		case '@':
+			if fd.repetitions > pos {
+				field.Offset, field.Size = pos, fd.repetitions-pos
+			} else {
+				field.Offset = fd.repetitions
+			}
+		default:
+			field.Offset, field.Size = pos, fd.size*fd.repetitions
+		}
+
+		if field.Size < 0 || (!known && fd.code == '@') {
+			// '*' reads the rest of the input
+			known = false
+			field.Size = -1
+		}
+		if known {
+			pos = field.Offset + field.Size
+		} else {
+			field.Offset = -1
+		}
+		fields[i] = field
+	}
+	return fields
+}
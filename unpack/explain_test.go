@@ -0,0 +1,53 @@
+package unpack
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	cases := []struct {
+		Format   string
+		Expected []string
+	}{
+		{"c2chars/n2int", []string{
+			`0-1 c2 "chars" 2 bytes signed int64`,
+			`2-5 n2 "int" 4 bytes big-endian unsigned int64`,
+		}},
+		{"Nlen/x2/A16name/@24/H4tag/X2/Vcrc", []string{
+			`0-3 N "len" 4 bytes big-endian unsigned int64`,
+			`4-5 x2 2 bytes`,
+			`6-21 A16 "name" 16 bytes []uint8`,
+			`22-23 @24 2 bytes`,
+			`24-25 H4 "tag" 2 bytes []uint8`,
+			`24 X2 0 bytes`,
+			`24-27 V "crc" 4 bytes little-endian unsigned int64`,
+		}},
+		{"C/a*rest/C", []string{
+			`0 C 1 byte unsigned int64`,
+			`? a* "rest" ? bytes []uint8`,
+			`? C 1 byte unsigned int64`,
+		}},
+	}
+
+	for _, c := range cases {
+		fields, err := Explain(c.Format)
+		if err != nil {
+			t.Errorf("explain %s failed: %v\n", c.Format, err)
+			continue
+		}
+		if len(fields) != len(c.Expected) {
+			t.Errorf("explain %s error, expected %d fields, actual: %v\n", c.Format, len(c.Expected), fields)
+			continue
+		}
+		for i, field := range fields {
+			if field.String() != c.Expected[i] {
+				t.Errorf("explain %s error, field %d expected: %s, actual: %s\n", c.Format, i, c.Expected[i], field)
+			}
+		}
+	}
+
+	if _, err := Explain("Y"); !errors.Is(err, ErrFormat) {
+		t.Errorf("explain of an unknown code should fail, err: %v\n", err)
+	}
+}
@@ -0,0 +1,189 @@
+package unpack
+
+import (
+	"fmt"
+	"github.com/xycczZ/php_pack/internal/diag"
+	"github.com/xycczZ/php_pack/internal/format"
+	"github.com/xycczZ/php_pack/internal/utils"
+	"github.com/xycczZ/php_pack/target"
+	"math"
+)
+
+// Format is a compiled unpack format string.
+// It is immutable once compiled and can be shared between goroutines.
+type Format struct {
+	format string
+	fields []field
+
+	// fixed is set when no field consumes the rest of the input ('*')
+	fixed bool
+	// size is the number of input bytes the format needs, only valid when fixed
+	size int
+	// prefix is the number of input bytes needed before the first field using '*'
+	prefix int
+	// tail is set when the only field using '*' is the last one,
+	// it then takes the rest of the input
+	tail bool
+
+	config   Config
+	platform target.Platform
+	// warnings found while compiling, reported by every Unpack call like php does
+	parseWarnings []Warning
+}
+
+type field struct {
+	code        byte
+	repetitions int
+	// argb is the count as written in the format, before it is turned into a size
+	argb int
+	name string
+	// size of one repetition, -1 for X
+	size int
+	// pos is the position of code in the format string
+	pos int
+}
+
+// Compile parses format once so that it can be used for many Unpack calls.
+func Compile(format string) (*Format, error) {
+	return CompileConfig(format, Config{})
+}
+
+// CompileConfig is Compile with the warning handling of config.
+// In strict mode, a format that warns on every call fails to compile.
+func CompileConfig(format string, config Config) (*Format, error) {
+	f, err := compile(format, config)
+	if err != nil {
+		return nil, (&diag.Warner{Config: &config}).Fail(err)
+	}
+
+	if config.Strict && len(f.parseWarnings) > 0 {
+		return nil, &WarningError{Warning: f.parseWarnings[0]}
+	}
+	return f, nil
+}
+
+func compile(format string, config Config) (*Format, error) {
+	fields := parse(format)
+	f := &Format{
+		config:   config,
+		platform: config.Platform.OrHost(),
+		format:   format,
+		fields:   make([]field, 0, len(fields)),
+		fixed:    true,
+	}
+
+	pos := 0
+	for _, fd := range fields {
+		theType := fd.Code
+		repetitions := fd.Arg
+		argb := repetitions
+		size := 0
+
+		if !config.Version.HasCode(theType) {
+			return nil, &FormatError{Code: theType, Pos: fd.Pos, Msg: "unknown format code"}
+		}
+		if !f.platform.HasCode(theType) {
+			return nil, &FormatError{Code: theType, Pos: fd.Pos, Msg: "64-bit format codes are not available for 32-bit versions of PHP"}
+		}
+		if (theType == 'i' || theType == 'I') && !f.platform.ValidIntSize() {
+			return nil, &FormatError{Code: theType, Pos: fd.Pos, Msg: fmt.Sprintf("sizeof(int) of %d is not 2, 4 or 8", f.platform.IntSize)}
+		}
+
+		if repetitions > math.MaxInt32 {
+			return nil, &FormatError{Code: theType, Pos: fd.Pos, Msg: "integer overflow"}
+		}
+
+		switch theType {
+		// Never use any input
+		case 'X':
+			size = -1
+			if repetitions < 0 {
+				f.parseWarnings = append(f.parseWarnings, Warning{Code: theType, Msg: "'*' ignored"})
+				repetitions = 1
+			}
+		case '@':
+			size = 0
+		case 'a', 'A', 'Z':
+			size = repetitions
+			repetitions = 1
+		case 'h', 'H':
+			if repetitions > math.MaxInt32/2 {
+				return nil, &FormatError{Code: theType, Pos: fd.Pos, Msg: fmt.Sprintf("repeater must be less than or equal to %d", math.MaxInt32/2)}
+			}
+			size = utils.If(repetitions > 0, (repetitions+(repetitions%2))/2, repetitions)
+			repetitions = 1
+		case 'c', 'C', 'x':
+			size = 1
+		case 's', 'S', 'n', 'v':
+			size = 2
+		case 'i', 'I':
+			size = f.platform.IntSize // sizeof(int)
+		case 'l', 'L', 'N', 'V':
+			size = 4
+		case 'q', 'Q', 'J', 'P':
+			size = 8
+		case 'f', 'g', 'G':
+			size = 4 // sizeof(float)
+		case 'd', 'e', 'E':
+			size = 8 // sizeof(double)
+		default:
+			return nil, &FormatError{Code: theType, Pos: fd.Pos, Msg: "unknown format code"}
+		}
+
+		// a, A, Z, h and H with '*' have a size of -1 and take the rest of the input like '*' counts
+		if repetitions < 0 || (size == -1 && theType != 'X') {
+			if f.fixed {
+				f.prefix = f.size
+				f.tail = true
+			} else {
+				f.tail = false
+			}
+			f.fixed = false
+		} else if !f.fixed {
+			f.tail = false
+		} else {
+			switch theType {
+			case '@':
+				pos = repetitions
+			case 'X':
+				pos = utils.Max(0, pos-repetitions)
+			default:
+				if size > 0 && repetitions > (math.MaxInt-pos)/size {
+					return nil, &FormatError{Code: theType, Pos: fd.Pos, Msg: "integer overflow"}
+				}
+				pos += size * repetitions
+			}
+			f.size = utils.Max(f.size, pos)
+		}
+
+		f.fields = append(f.fields, field{
+			code:        theType,
+			repetitions: repetitions,
+			argb:        argb,
+			name:        fd.Name,
+			size:        size,
+			pos:         fd.Pos,
+		})
+	}
+
+	if !f.fixed {
+		f.size = 0
+	}
+
+	return f, nil
+}
+
+// String returns the source format string.
+func (f *Format) String() string {
+	return f.format
+}
+
+// Size returns the number of input bytes the format reads, and false when it
+// depends on the input.
+func (f *Format) Size() (int, bool) {
+	return f.size, f.fixed
+}
+
+func parse(f string) []format.Field {
+	return format.Parse(f, true)
+}
@@ -0,0 +1,133 @@
+package unpack
+
+import (
+	"github.com/xycczZ/php_pack/pack"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	cases := []struct {
+		Format string
+		Size   int
+		Fixed  bool
+	}{
+		{"N", 4, true},
+		{"c2chars/n2int", 6, true},
+		{"a4str/x2/@2/n", 6, true},
+		{"Cnum/X/C", 1, true},
+		{"Nlen/a*data", 0, false},
+	}
+
+	for i := range cases {
+		f, err := Compile(cases[i].Format)
+		if err != nil {
+			t.Errorf("compile failed, format: %s, err: %v\n", cases[i].Format, err)
+			return
+		}
+
+		size, fixed := f.Size()
+		if size != cases[i].Size || fixed != cases[i].Fixed {
+			t.Errorf("size error, format: %s, expected: %d %v, actual: %d %v\n", cases[i].Format, cases[i].Size, cases[i].Fixed, size, fixed)
+			return
+		}
+	}
+
+	if _, err := Compile("Nlen/ydata"); err == nil {
+		t.Errorf("compile should fail on unknown format code\n")
+	}
+
+	// the size of a fixed format only overflows an int of 32 bits
+	if strconv.IntSize == 32 {
+		if _, err := Compile("d2000000000"); err == nil {
+			t.Errorf("compile should fail on integer overflow\n")
+		}
+	}
+}
+
+func TestFormatUnpack(t *testing.T) {
+	bin, err := pack.PHPPack("NnC", 0x01020304, 0x0506, 7)
+	if err != nil {
+		t.Errorf("pack failed: %v\n", err)
+		return
+	}
+
+	f, err := Compile("Nlong/nshort/Cchar")
+	if err != nil {
+		t.Errorf("compile failed: %v\n", err)
+		return
+	}
+
+	expected := map[string]any{"long": int64(0x01020304), "short": int64(0x0506), "char": int64(7)}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				r, err := f.Unpack(bin, 0)
+				if err != nil {
+					t.Errorf("unpack failed: %v\n", err)
+					return
+				}
+				if !mapEq(r, expected) {
+					t.Errorf("unpack error, expected: %v, actual: %v\n", expected, r)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	r, err := f.Unpack(append([]byte{0xff, 0xff}, bin...), 2)
+	if err != nil {
+		t.Errorf("unpack with offset failed: %v\n", err)
+		return
+	}
+	if !mapEq(r, expected) {
+		t.Errorf("unpack with offset error, expected: %v, actual: %v\n", expected, r)
+	}
+}
+
+func TestFormatUnpackArray(t *testing.T) {
+	bin, err := pack.PHPPack("c2n2C", 0x1234, 0x5678, 65, 66, 67)
+	if err != nil {
+		t.Errorf("pack failed: %v\n", err)
+		return
+	}
+
+	r, err := PHPUnpackArray(NewOption("c2chars/n2int/C", bin))
+	if err != nil {
+		t.Errorf("unpack failed: %v\n", err)
+		return
+	}
+
+	expectedKeys := []string{"chars1", "chars2", "int1", "int2", "1"}
+	expectedValues := []int64{52, 120, 65, 66, 67}
+	keys := r.Keys()
+	if len(keys) != len(expectedKeys) {
+		t.Errorf("keys error, expected: %v, actual: %v\n", expectedKeys, keys)
+		return
+	}
+	for i, k := range keys {
+		v, _ := r.Get(k)
+		if k.String() != expectedKeys[i] || v != expectedValues[i] {
+			t.Errorf("element %d error, expected: %s => %d, actual: %s => %v\n", i, expectedKeys[i], expectedValues[i], k, v)
+		}
+	}
+	if !keys[4].IsInt() {
+		t.Errorf("unnamed field should have an integer key\n")
+	}
+
+	// the second unnamed field overwrites the first one, like php
+	r, err = PHPUnpackArray(NewOption("C/C", []byte{1, 2}))
+	if err != nil {
+		t.Errorf("unpack failed: %v\n", err)
+		return
+	}
+	if v, ok := r.Get(r.Keys()[0]); r.Len() != 1 || !ok || v != int64(2) {
+		t.Errorf("duplicate key error, actual: %v\n", r)
+	}
+}
@@ -0,0 +1,153 @@
+package unpack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/xycczZ/php_pack/pack"
+	"math"
+	"strings"
+	"testing"
+)
+
+// FuzzUnpack checks that unpack never panics or spins, whatever the format and the input.
+func FuzzUnpack(f *testing.F) {
+	f.Add("c2chars/nint", []byte("\x04\x03\x12\x34"), 0)
+	f.Add("A*", []byte("ab \x00\t"), 0)
+	f.Add("C12", []byte("0123456789ab"), 0)
+	f.Add("fa/db", make([]byte, 12), 0)
+	f.Add("h*a/H3b", []byte{0x12, 0x34}, 1)
+	f.Add("X999999999999/Ca", []byte{1}, 0)
+	f.Add("@5/a*/Z9", []byte("hello"), 2)
+	f.Add("h9223372036854775807", []byte{1}, 0)
+
+	f.Fuzz(func(t *testing.T, format string, data []byte, offset int) {
+		option := NewOption(format, data)
+		option.Offset = offset
+		r, err := PHPUnpackArray(option)
+		if err != nil {
+			return
+		}
+
+		// every value comes from the input
+		for _, v := range r.Values() {
+			if b, ok := v.([]byte); ok && len(b) > 2*len(data) {
+				t.Errorf("unpack %q: value of %d bytes from %d bytes of input\n", format, len(b), len(data))
+			}
+		}
+	})
+}
+
+// roundTripCodes are the codes for which unpack(pack(x)) == x, with the range of their values.
+var roundTripCodes = []struct {
+	code     byte
+	min, max int64
+}{
+	{'c', math.MinInt8, math.MaxInt8},
+	{'C', 0, math.MaxUint8},
+	{'s', math.MinInt16, math.MaxInt16},
+	{'S', 0, math.MaxUint16},
+	{'n', 0, math.MaxUint16},
+	{'v', 0, math.MaxUint16},
+	{'l', math.MinInt32, math.MaxInt32},
+	{'L', 0, math.MaxUint32},
+	{'N', 0, math.MaxUint32},
+	{'V', 0, math.MaxUint32},
+	{'i', math.MinInt32, math.MaxInt32},
+	{'I', 0, math.MaxUint32},
+	{'q', math.MinInt64, math.MaxInt64},
+	{'Q', math.MinInt64, math.MaxInt64},
+	{'J', math.MinInt64, math.MaxInt64},
+	{'P', math.MinInt64, math.MaxInt64},
+}
+
+// FuzzRoundTrip packs values generated from data and checks that unpack returns them.
+func FuzzRoundTrip(f *testing.F) {
+	f.Add([]byte("\x00\x01\x02\x03\x04\x05\x06\x07\x08"))
+	f.Add([]byte("\x10\xff\xff\xff\xff\xff\xff\xff\x7f\x11hello\x12\x00\x00\xc0\x7f"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var format, unpackFormat []string
+		var args []any
+		var expected []any
+
+		for i := 0; len(data) >= 9; i++ {
+			kind := data[0]
+			x := binary.LittleEndian.Uint64(data[1:9])
+			data = data[9:]
+			name := fmt.Sprintf("k%d", i)
+
+			switch n := int(kind) % (len(roundTripCodes) + 4); {
+			case n < len(roundTripCodes):
+				rc := roundTripCodes[n]
+				v := int64(x)
+				if rc.min != math.MinInt64 || rc.max != math.MaxInt64 {
+					v = rc.min + int64(x%uint64(rc.max-rc.min+1))
+				}
+				format = append(format, string(rc.code))
+				unpackFormat = append(unpackFormat, string(rc.code)+name)
+				args = append(args, v)
+				expected = append(expected, v)
+			case n == len(roundTripCodes):
+				v := math.Float64frombits(x)
+				if math.IsNaN(v) {
+					continue
+				}
+				code := string("deE"[x%3])
+				format = append(format, code)
+				unpackFormat = append(unpackFormat, code+name)
+				args = append(args, v)
+				expected = append(expected, v)
+			case n == len(roundTripCodes)+1:
+				v := float64(math.Float32frombits(uint32(x)))
+				if math.IsNaN(v) {
+					continue
+				}
+				code := string("fgG"[x%3])
+				format = append(format, code)
+				unpackFormat = append(unpackFormat, code+name)
+				args = append(args, v)
+				expected = append(expected, v)
+			case n == len(roundTripCodes)+2:
+				// a string of its exact length
+				s := fmt.Sprintf("%x", x)
+				format = append(format, fmt.Sprintf("a%d", len(s)))
+				unpackFormat = append(unpackFormat, fmt.Sprintf("a%d%s", len(s), name))
+				args = append(args, s)
+				expected = append(expected, []byte(s))
+			default:
+				s := fmt.Sprintf("%x", x)
+				format = append(format, fmt.Sprintf("H%d", len(s)))
+				unpackFormat = append(unpackFormat, fmt.Sprintf("H%d%s", len(s), name))
+				args = append(args, s)
+				expected = append(expected, []byte(s))
+			}
+		}
+		if len(format) == 0 {
+			return
+		}
+
+		packed, err := pack.PHPPack(strings.Join(format, ""), args...)
+		if err != nil {
+			t.Fatalf("pack %s failed: %v\n", strings.Join(format, ""), err)
+		}
+		r, err := PHPUnpackArray(NewOption(strings.Join(unpackFormat, "/"), packed))
+		if err != nil {
+			t.Fatalf("unpack %s failed: %v\n", strings.Join(unpackFormat, "/"), err)
+		}
+
+		values := r.Values()
+		if len(values) != len(expected) {
+			t.Fatalf("round trip %s error, expected: %v, actual: %v\n", strings.Join(format, ""), expected, values)
+		}
+		for i := range values {
+			if b, ok := expected[i].([]byte); ok {
+				if !bytes.Equal(values[i].([]byte), b) {
+					t.Errorf("round trip %s value %d error, expected: %s, actual: %s\n", format[i], i, b, values[i])
+				}
+			} else if values[i] != expected[i] {
+				t.Errorf("round trip %s value %d error, expected: %v, actual: %v\n", format[i], i, expected[i], values[i])
+			}
+		}
+	})
+}
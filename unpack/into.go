@@ -0,0 +1,217 @@
+package unpack
+
+import (
+	"errors"
+	"fmt"
+	"github.com/xycczZ/php_pack/internal/tags"
+	"github.com/xycczZ/php_pack/phparray"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type structFormat struct {
+	format *Format
+	fields []tags.Field
+}
+
+var structFormats sync.Map // reflect.Type -> *structFormat
+
+// Into unpacks data into the struct v points to.
+// The format is derived from the `php` struct tags, see StructFormat.
+//
+// Integer codes decode into integer fields, float codes into float fields and
+// a, A, Z, h, H into string, []byte or [N]byte fields.
+// A count greater than one, or '*', needs a slice or array field.
+// A value that does not fit its field is an *ArgumentError, its Index is the position of the field among the tagged ones.
+func Into(data []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("unpack into: need a non-nil pointer to a struct, got %T", v)
+	}
+	rv = rv.Elem()
+
+	sf, err := compileStruct(rv.Type())
+	if err != nil {
+		return err
+	}
+
+	result, err := sf.format.UnpackArray(data, 0)
+	if err != nil {
+		return err
+	}
+
+	for i := range sf.fields {
+		field := &sf.fields[i]
+		if tags.IsPosition(field.Code) {
+			continue
+		}
+		if err := decodeField(field, i, rv.FieldByIndex(field.Index), result); err != nil {
+			return fmt.Errorf("unpack into: field %s: %w", field.GoName, err)
+		}
+	}
+
+	return nil
+}
+
+// StructFormat returns the unpack format derived from the `php` tags of the struct type of v,
+// e.g. "ntype/a16title".
+func StructFormat(v any) (string, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil {
+		return "", fmt.Errorf("unpack: nil type")
+	}
+
+	sf, err := compileStruct(t)
+	if err != nil {
+		return "", err
+	}
+	return sf.format.String(), nil
+}
+
+func compileStruct(t reflect.Type) (*structFormat, error) {
+	if sf, ok := structFormats.Load(t); ok {
+		return sf.(*structFormat), nil
+	}
+
+	s, err := tags.Of(t)
+	if err != nil {
+		return nil, err
+	}
+
+	directives := make([]string, len(s.Fields))
+	for i := range s.Fields {
+		directives[i] = s.Fields[i].Directive() + s.Fields[i].Name
+	}
+
+	f, err := Compile(strings.Join(directives, "/"))
+	if err != nil {
+		return nil, err
+	}
+
+	sf := &structFormat{format: f, fields: s.Fields}
+	actual, _ := structFormats.LoadOrStore(t, sf)
+	return actual.(*structFormat), nil
+}
+
+// decodeField sets the struct field fv from result, index is its position in the tagged fields.
+func decodeField(field *tags.Field, index int, fv reflect.Value, result *phparray.Array) error {
+	if tags.IsString(field.Code) {
+		v, ok := result.GetString(field.Name)
+		if !ok {
+			return &ArgumentError{Code: field.Code, Index: index, Msg: fmt.Sprintf("no value for key %q", field.Name)}
+		}
+		return setValue(field.Code, index, fv, v)
+	}
+
+	// php uses the bare name only for a count of one, else name1, name2...
+	if field.Arg == 1 {
+		v, ok := result.GetString(field.Name)
+		if !ok {
+			return &ArgumentError{Code: field.Code, Index: index, Msg: fmt.Sprintf("no value for key %q", field.Name)}
+		}
+		switch fv.Kind() {
+		case reflect.Slice:
+			fv.Set(reflect.MakeSlice(fv.Type(), 1, 1))
+			return setValue(field.Code, index, fv.Index(0), v)
+		case reflect.Array:
+			if fv.Len() < 1 {
+				return &ArgumentError{Code: field.Code, Index: index, Type: fv.Type(), Msg: "array of length 0 can not hold a value"}
+			}
+			return setValue(field.Code, index, fv.Index(0), v)
+		}
+		return setValue(field.Code, index, fv, v)
+	}
+
+	var values []any
+	for i := 1; field.Arg < 0 || i <= field.Arg; i++ {
+		v, ok := result.GetString(field.Name + strconv.Itoa(i))
+		if !ok {
+			break
+		}
+		values = append(values, v)
+	}
+
+	switch fv.Kind() {
+	case reflect.Slice:
+		fv.Set(reflect.MakeSlice(fv.Type(), len(values), len(values)))
+	case reflect.Array:
+		if len(values) > fv.Len() {
+			return &ArgumentError{Code: field.Code, Index: index, Type: fv.Type(), Msg: fmt.Sprintf("%d values do not fit", len(values))}
+		}
+		fv.SetZero()
+	default:
+		return &ArgumentError{Code: field.Code, Index: index, Type: fv.Type(), Msg: fmt.Sprintf("count %s needs a slice or array", countString(field.Arg))}
+	}
+
+	for i, v := range values {
+		if err := setValue(field.Code, index, fv.Index(i), v); err != nil {
+			var ae *ArgumentError
+			if errors.As(err, &ae) {
+				ae.Msg = fmt.Sprintf("element %d: %s", i, ae.Msg)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func setValue(code byte, index int, fv reflect.Value, v any) error {
+	switch x := v.(type) {
+	case []byte:
+		switch {
+		case fv.Kind() == reflect.String:
+			fv.SetString(string(x))
+			return nil
+		case fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.Uint8:
+			fv.SetBytes(append([]byte(nil), x...))
+			return nil
+		case fv.Kind() == reflect.Array && fv.Type().Elem().Kind() == reflect.Uint8:
+			if len(x) > fv.Len() {
+				return &ArgumentError{Code: code, Index: index, Type: fv.Type(), Msg: fmt.Sprintf("%d bytes do not fit", len(x))}
+			}
+			fv.SetZero()
+			reflect.Copy(fv, reflect.ValueOf(x))
+			return nil
+		}
+	case int64:
+		switch fv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if fv.OverflowInt(x) {
+				return &ArgumentError{Code: code, Index: index, Type: fv.Type(), Msg: fmt.Sprintf("value %d overflows", x)}
+			}
+			fv.SetInt(x)
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			// php has no unsigned 64 bit integer, Q, J and P come back as their two's complement
+			if (code == 'Q' || code == 'J' || code == 'P') && fv.Type().Size() == 8 {
+				fv.SetUint(uint64(x))
+				return nil
+			}
+			if x < 0 || fv.OverflowUint(uint64(x)) {
+				return &ArgumentError{Code: code, Index: index, Type: fv.Type(), Msg: fmt.Sprintf("value %d overflows", x)}
+			}
+			fv.SetUint(uint64(x))
+			return nil
+		}
+	case float64:
+		switch fv.Kind() {
+		case reflect.Float32, reflect.Float64:
+			fv.SetFloat(x)
+			return nil
+		}
+	}
+
+	return &ArgumentError{Code: code, Index: index, Type: fv.Type(), Msg: "can not unpack into this type"}
+}
+
+func countString(arg int) string {
+	if arg < 0 {
+		return "*"
+	}
+	return strconv.Itoa(arg)
+}
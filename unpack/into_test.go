@@ -0,0 +1,126 @@
+package unpack
+
+import (
+	"errors"
+	"github.com/xycczZ/php_pack/pack"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type header struct {
+	Type    uint16   `php:"n"`
+	Flags   uint8    `php:"C"`
+	_       struct{} `php:"x"`
+	Title   string   `php:"a8,name=title"`
+	Tag     [4]byte  `php:"A"`
+	Vals    []int32  `php:"N2,name=vals"`
+	ID      uint64   `php:"J"`
+	Ignored string
+	Rest    []byte `php:"a*"`
+}
+
+func TestInto(t *testing.T) {
+	format, err := StructFormat(&header{})
+	if err != nil {
+		t.Errorf("struct format failed: %v\n", err)
+		return
+	}
+	if format != "nType/CFlags/x/a8title/A4Tag/N2vals/JID/a*Rest" {
+		t.Errorf("struct format error, actual: %s\n", format)
+	}
+
+	bin, err := pack.PHPPack("nCxa8A4N2Ja*", 0x0102, 3, "hello", "ab", 10, 20, -1, "tail")
+	if err != nil {
+		t.Errorf("pack failed: %v\n", err)
+		return
+	}
+
+	var h header
+	if err := Into(bin, &h); err != nil {
+		t.Errorf("unpack into failed: %v\n", err)
+		return
+	}
+
+	expected := header{
+		Type:  0x0102,
+		Flags: 3,
+		Title: "hello\000\000\000",
+		Tag:   [4]byte{'a', 'b'},
+		Vals:  []int32{10, 20},
+		ID:    1<<64 - 1,
+		Rest:  []byte("tail"),
+	}
+	if !reflect.DeepEqual(h, expected) {
+		t.Errorf("unpack into error, expected: %+v, actual: %+v\n", expected, h)
+	}
+}
+
+func TestIntoErrors(t *testing.T) {
+	cases := []struct {
+		V     any
+		Input []byte
+		Err   string
+	}{
+		{&struct {
+			V int8 `php:"n"`
+		}{}, []byte{0x01, 0x00}, "argument 0 (int8): value 256 overflows"},
+		{&struct {
+			V uint16 `php:"n2"`
+		}{}, []byte{0, 1, 0, 2}, "needs a slice or array"},
+		{&struct {
+			V float32 `php:"n"`
+		}{}, []byte{0, 1}, "type n: argument 0 (float32): can not unpack into this type"},
+		{&struct {
+			V []uint16 `php:"n"`
+		}{}, []byte{0, 1}, "needs a count"},
+		{&struct {
+			V []uint16 `php:"n1"`
+		}{}, []byte{0, 1}, ""},
+		{&struct {
+			V []uint16 `php:"v*"`
+		}{}, []byte{0, 1}, ""},
+		{&struct {
+			V uint16 `php:"y"`
+		}{}, []byte{0, 1}, "unknown format code"},
+		{&struct {
+			V uint16 `php:"nn"`
+		}{}, []byte{0, 1}, "exactly one format code"},
+		{&struct {
+			V []uint16 `php:"n,size=2"`
+		}{}, []byte{0, 1}, "unknown tag option"},
+		{&struct {
+			V uint32 `php:"N"`
+		}{}, []byte{0, 1}, "not enough input"},
+		{&struct {
+			V uint16 `php:"n,name=1st"`
+		}{}, []byte{0, 1}, `name "1st" must not start with a digit or '*'`},
+		{&struct {
+			V []byte `php:"a2,name=*x"`
+		}{}, []byte{0, 1}, `name "*x" must not start with a digit or '*'`},
+		{struct{}{}, nil, "pointer to a struct"},
+	}
+
+	for i := range cases {
+		err := Into(cases[i].Input, cases[i].V)
+		if cases[i].Err == "" {
+			if err != nil {
+				t.Errorf("case %d: unpack into failed: %v\n", i, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), cases[i].Err) {
+			t.Errorf("case %d: error should contain %q, actual: %v\n", i, cases[i].Err, err)
+		}
+	}
+
+	err := Into([]byte{0, 1, 0, 2, 0xff, 0}, &struct {
+		A uint16   `php:"n"`
+		B [1]uint8 `php:"n"`
+		C int8     `php:"v"`
+	}{})
+	var ae *ArgumentError
+	if !errors.Is(err, ErrArgument) || !errors.As(err, &ae) || ae.Code != 'v' || ae.Index != 2 || ae.Type != reflect.TypeOf(int8(0)) {
+		t.Errorf("argument error, expected: type v, index 2, int8, actual: %#v\n", err)
+	}
+}
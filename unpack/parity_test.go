@@ -0,0 +1,57 @@
+package unpack
+
+import (
+	"encoding/hex"
+	"github.com/xycczZ/php_pack/target"
+	"testing"
+)
+
+func TestParity(t *testing.T) {
+	cases := []struct {
+		Format   string
+		Hex      string
+		Expected map[string]any
+	}{
+		{"C2", "ff7f", map[string]any{"1": int64(255), "2": int64(127)}},
+		{"S2", "0080ffff", map[string]any{"1": int64(0x8000), "2": int64(0xffff)}},
+		{"n2", "ffff1234", map[string]any{"1": int64(0xffff), "2": int64(0x1234)}},
+		{"v2", "ffff3412", map[string]any{"1": int64(0xffff), "2": int64(0x1234)}},
+		{"i2", "ffffffff78563412", map[string]any{"1": int64(-1), "2": int64(0x12345678)}},
+		{"I", "ffffffff", map[string]any{"1": int64(0xffffffff)}},
+		{"L2", "feffffff01000000", map[string]any{"1": int64(0xfffffffe), "2": int64(1)}},
+		{"N2", "ffffffff12345678", map[string]any{"1": int64(0xffffffff), "2": int64(0x12345678)}},
+		{"V2", "ffffffff78563412", map[string]any{"1": int64(0xffffffff), "2": int64(0x12345678)}},
+		{"q2", "feffffffffffffff0807060504030201", map[string]any{"1": int64(-2), "2": int64(0x0102030405060708)}},
+		{"J2", "fffffffffffffffe0102030405060708", map[string]any{"1": int64(-2), "2": int64(0x0102030405060708)}},
+		{"P2", "feffffffffffffff0807060504030201", map[string]any{"1": int64(-2), "2": int64(0x0102030405060708)}},
+		{"f2", "0000c03f0000a0c0", map[string]any{"1": 1.5, "2": -5.0}},
+		{"g2", "0000c03f0000a0c0", map[string]any{"1": 1.5, "2": -5.0}},
+		{"G2", "3fc00000c0a00000", map[string]any{"1": 1.5, "2": -5.0}},
+		{"d2", "000000000000f83f9a9999999999b9bf", map[string]any{"1": 1.5, "2": -0.1}},
+		{"e2", "000000000000f83f9a9999999999b9bf", map[string]any{"1": 1.5, "2": -0.1}},
+		{"E2", "3ff8000000000000bfb999999999999a", map[string]any{"1": 1.5, "2": -0.1}},
+	}
+
+	config := Config{Platform: target.AMD64}
+	for _, c := range cases {
+		input, _ := hex.DecodeString(c.Hex)
+		f, err := CompileConfig(c.Format, config)
+		if err != nil {
+			t.Errorf("compile %s failed: %v\n", c.Format, err)
+			continue
+		}
+
+		// the input is read at every alignment
+		for offset := 0; offset < 8; offset++ {
+			data := append(make([]byte, offset), input...)
+			r, err := f.Unpack(data, offset)
+			if err != nil {
+				t.Errorf("unpack %s at %d failed: %v\n", c.Format, offset, err)
+				continue
+			}
+			if !mapEq(r, c.Expected) {
+				t.Errorf("unpack %s at %d error, expected: %v, actual: %v\n", c.Format, offset, c.Expected, r)
+			}
+		}
+	}
+}
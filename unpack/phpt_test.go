@@ -0,0 +1,94 @@
+package unpack
+
+import (
+	"bytes"
+	"encoding/hex"
+	"github.com/xycczZ/php_pack/internal/phpt"
+	"github.com/xycczZ/php_pack/phparray"
+	"testing"
+)
+
+// TestPHPT runs the unpack expectations of php-src's .phpt tests, see testdata/phpt.
+func TestPHPT(t *testing.T) {
+	files, err := phpt.Load("../testdata/phpt")
+	if err != nil {
+		t.Fatalf("load phpt failed: %v\n", err)
+	}
+
+	for _, file := range files {
+		platform, err := file.Target()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range file.Unpack {
+			input, err := hex.DecodeString(c.Hex)
+			if err != nil {
+				t.Errorf("%s: %s: %v\n", file.Name, c.Format, err)
+				continue
+			}
+
+			option := NewOption(c.Format, input)
+			option.Offset = c.Offset
+			option.Platform = platform
+			r, err := PHPUnpackArray(option)
+			if c.Error != "" {
+				if err == nil || !phpt.Message(err.Error(), c.Error) {
+					t.Errorf("%s: unpack %s error, expected: %s, actual: %v\n", file.Name, c.Format, c.Error, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("%s: unpack %s failed: %v\n", file.Name, c.Format, err)
+				continue
+			}
+
+			if !resultEq(t, r.Keys(), r.Values(), c.Result) {
+				t.Errorf("%s: unpack %s %s error, expected: %v, actual: %v\n", file.Name, c.Format, c.Hex, c.Result, r.Map())
+			}
+			if !warningsEq(option.Warnings, c.Warnings) {
+				t.Errorf("%s: unpack %s warnings error, expected: %v, actual: %v\n", file.Name, c.Format, c.Warnings, option.Warnings)
+			}
+		}
+	}
+}
+
+// resultEq compares the keys and values of an unpack result in order.
+func resultEq(t *testing.T, keys []phparray.Key, values []any, expected [][2]string) bool {
+	if len(keys) != len(expected) {
+		return false
+	}
+	for i := range keys {
+		if keys[i].String() != expected[i][0] {
+			return false
+		}
+
+		v, err := phpt.Value(expected[i][1]).Decode()
+		if err != nil {
+			t.Errorf("%v\n", err)
+			return false
+		}
+		switch v := v.(type) {
+		case []byte:
+			if b, ok := values[i].([]byte); !ok || !bytes.Equal(b, v) {
+				return false
+			}
+		default:
+			if values[i] != v {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func warningsEq(actual []Warning, expected []string) bool {
+	if len(actual) != len(expected) {
+		return false
+	}
+	for i := range actual {
+		if !phpt.Message(actual[i].String(), expected[i]) {
+			return false
+		}
+	}
+	return true
+}
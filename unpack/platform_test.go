@@ -0,0 +1,99 @@
+package unpack
+
+import (
+	"errors"
+	"fmt"
+	"github.com/xycczZ/php_pack/target"
+	"testing"
+)
+
+func TestPlatforms(t *testing.T) {
+	int16BE := target.Platform{BigEndian: true, IntSize: 2, Is64Bit: true}
+	int64LE := target.Platform{BigEndian: false, IntSize: 8, Is64Bit: true}
+
+	cases := []struct {
+		Platform target.Platform
+		Format   string
+		Input    string
+		Expected map[string]any
+	}{
+		{target.AMD64, "Sa/ib/Ic/Ld", "\x02\x01\xfe\xff\xff\xff\xfe\xff\xff\xff\x0c\x0b\x0a\x09",
+			map[string]any{"a": int64(0x0102), "b": int64(-2), "c": int64(0xfffffffe), "d": int64(0x090a0b0c)}},
+		{target.S390X, "Sa/ib/Ic/Ld", "\x01\x02\xff\xff\xff\xfe\xff\xff\xff\xfe\x09\x0a\x0b\x0c",
+			map[string]any{"a": int64(0x0102), "b": int64(-2), "c": int64(0xfffffffe), "d": int64(0x090a0b0c)}},
+		{target.AMD64, "qa/Qb", "\x08\x07\x06\x05\x04\x03\x02\x01\xff\xff\xff\xff\xff\xff\xff\xff",
+			map[string]any{"a": int64(0x0102030405060708), "b": int64(-1)}},
+		{target.PPC64, "q", "\x01\x02\x03\x04\x05\x06\x07\x08", map[string]any{"1": int64(0x0102030405060708)}},
+		{target.AMD64, "fa/db", "\x00\x00\xc0\x3f\x00\x00\x00\x00\x00\x00\x04\xc0",
+			map[string]any{"a": 1.5, "b": -2.5}},
+		{target.S390X, "fa/db", "\x3f\xc0\x00\x00\xc0\x04\x00\x00\x00\x00\x00\x00",
+			map[string]any{"a": 1.5, "b": -2.5}},
+		// fixed byte order codes do not depend on the platform
+		{target.S390X, "na/vb/gc/Ed", "\x00\x01\x01\x00\x00\x00\xc0\x3f\x3f\xf8\x00\x00\x00\x00\x00\x00",
+			map[string]any{"a": int64(1), "b": int64(1), "c": 1.5, "d": 1.5}},
+		// a 32-bit zend_long wraps unsigned values
+		{target.I386, "Ia/Nb/Vc", "\xfe\xff\xff\xff\xff\xff\xff\xfe\x01\x00\x00\x00",
+			map[string]any{"a": int64(-2), "b": int64(-2), "c": int64(1)}},
+		{int16BE, "i2", "\x01\x02\xff\xfe", map[string]any{"1": int64(0x0102), "2": int64(-2)}},
+		{int64LE, "ia/Ib", "\xfe\xff\xff\xff\xff\xff\xff\xff\x08\x07\x06\x05\x04\x03\x02\x01",
+			map[string]any{"a": int64(-2), "b": int64(0x0102030405060708)}},
+	}
+
+	for _, c := range cases {
+		f, err := CompileConfig(c.Format, Config{Platform: c.Platform})
+		if err != nil {
+			t.Errorf("compile %s on %s failed: %v\n", c.Format, c.Platform, err)
+			continue
+		}
+		if size, ok := f.Size(); !ok || size != len(c.Input) {
+			t.Errorf("size of %s on %s error, expected: %d, actual: %d\n", c.Format, c.Platform, len(c.Input), size)
+		}
+
+		r, err := f.Unpack([]byte(c.Input), 0)
+		if err != nil {
+			t.Errorf("unpack %s on %s failed: %v\n", c.Format, c.Platform, err)
+			continue
+		}
+		if !mapEq(r, c.Expected) {
+			t.Errorf("unpack %s on %s error, expected: %v, actual: %v\n", c.Format, c.Platform, c.Expected, r)
+		}
+	}
+}
+
+func TestPlatform32Bit(t *testing.T) {
+	for _, format := range []string{"q", "Q", "J", "P", "Na/Jb"} {
+		option := NewOption(format, make([]byte, 16))
+		option.Platform = target.I386
+		_, err := PHPUnpack(option)
+
+		var fe *FormatError
+		if !errors.As(err, &fe) || fe.Msg != "64-bit format codes are not available for 32-bit versions of PHP" {
+			t.Errorf("unpack %s on 32-bit php should fail, err: %v\n", format, err)
+		}
+	}
+}
+
+func TestPlatformIntSize(t *testing.T) {
+	cases := []struct {
+		Platform target.Platform
+		Format   string
+	}{
+		{target.Platform{BigEndian: true}, "i"},
+		{target.Platform{BigEndian: true}, "i*"},
+		{target.Platform{IntSize: 3, Is64Bit: true}, "i2"},
+	}
+
+	for _, c := range cases {
+		option := NewOption(c.Format, []byte{1, 2, 3, 4})
+		option.Platform = c.Platform
+		_, err := PHPUnpack(option)
+		var fe *FormatError
+		if !errors.As(err, &fe) || fe.Msg != fmt.Sprintf("sizeof(int) of %d is not 2, 4 or 8", c.Platform.IntSize) {
+			t.Errorf("unpack %s on %#v should fail, err: %v\n", c.Format, c.Platform, err)
+		}
+	}
+
+	if _, err := CompileConfig("nN", Config{Platform: target.Platform{BigEndian: true}}); err != nil {
+		t.Errorf("compile without i failed: %v\n", err)
+	}
+}
@@ -2,17 +2,31 @@ package unpack
 
 import (
 	"fmt"
+	"github.com/xycczZ/php_pack/internal/diag"
+	"github.com/xycczZ/php_pack/internal/tags"
 	"github.com/xycczZ/php_pack/internal/utils"
-	"log"
+	"github.com/xycczZ/php_pack/phparray"
 	"math"
-	"strconv"
-	"unsafe"
 )
 
+// Config is the warning handling and the php version of unpack calls, shared with the pack package:
+// a callback, a slog.Logger, or strict mode which turns every warning into an error.
+type Config = diag.Config
+
 type Option struct {
+	// Config is the warning handling and the php version of the call
+	Config
 	Format string
 	Val    []byte
 	Offset int
+	// Slices returns the values of a numeric field with a count other than one, or '*', as one []int64
+	// or []float64 under the field name ("1" when unnamed), instead of php's keys name1, name2...
+	// A field that reads no value, like N0 or a '*' at the end of the input, sets no key.
+	// There is no [][]byte: the count of a, A, Z, h and H is a length, not a repetition,
+	// so they always unpack one []byte under the field name, with or without Slices.
+	Slices bool
+	// Warnings receives the warnings of the call
+	Warnings []Warning
 }
 
 func NewOption(format string, val []byte) *Option {
@@ -29,152 +43,142 @@ func NewOption(format string, val []byte) *Option {
 // x, X, @: 不返回值
 // 如果在format中没有指明key的，默认设置为字符串的索引, 从1开始, "1", "2"...
 func PHPUnpack(option *Option) (map[string]any, error) {
-	result := make(map[string]any)
+	result, err := PHPUnpackArray(option)
+	if err != nil {
+		return nil, err
+	}
+	return result.Map(), nil
+}
 
-	format := []byte(option.Format)
-	formatLen := len(format)
-	input := option.Val
-	inputLen := len(input)
-	inputPos := 0
-	offset := option.Offset
+// PHPUnpackArray is PHPUnpack returning the fields in format order,
+// keyed like the array php's unpack() returns.
+func PHPUnpackArray(option *Option) (*phparray.Array, error) {
+	option.Warnings = option.Warnings[:0]
 
-	if offset < 0 || offset > inputLen {
-		return nil, fmt.Errorf("offset error: %d\n", offset)
+	w := &diag.Warner{Config: &option.Config, Collected: &option.Warnings}
+	f, err := compile(option.Format, option.Config)
+	if err != nil {
+		return nil, w.Fail(err)
 	}
+	return f.unpack(option.Val, option.Offset, option.Slices, w, nil)
+}
 
-	input = input[offset:]
-	inputLen -= offset
+// Unpack unpacks data starting at offset according to the compiled format.
+func (f *Format) Unpack(data []byte, offset int) (map[string]any, error) {
+	result, err := f.UnpackArray(data, offset)
+	if err != nil {
+		return nil, err
+	}
+	return result.Map(), nil
+}
 
-	formatPos := 0
-
-	for formatLen > 0 {
-		formatLen--
-		repetitions := 1
-
-		theType := format[formatPos]
-		formatPos++
-		var argb int
-		var name []byte
-		var nameLen int
-		size := 0
-
-		var c byte
-		if formatLen > 0 {
-			c = format[formatPos]
-			if c >= '0' && c <= '9' {
-				var err error
-				repetitions, err = strconv.Atoi(string(format[formatPos]))
-				if err != nil {
-					return nil, err
-				}
+// UnpackArray is Unpack returning the fields in format order.
+// Unnamed fields get integer keys and a repeated key overwrites the earlier value in place, as in php.
+func (f *Format) UnpackArray(data []byte, offset int) (*phparray.Array, error) {
+	return f.unpack(data, offset, false, &diag.Warner{Config: &f.config}, nil)
+}
 
-				formatPos++
-				formatLen--
-				for formatLen > 0 && format[formatPos] >= '0' && format[formatPos] <= '9' {
-					repNext, err := strconv.Atoi(string(format[formatPos]))
-					if err != nil {
-						return nil, err
-					}
-					repetitions = repetitions*10 + repNext
-					formatPos++
-					formatLen--
-				}
-			} else if c == '*' {
-				repetitions = -1
-				formatPos++
-				formatLen--
-			}
-		}
+// UnpackSlices is Unpack returning repeated numeric fields as slices, see Option.Slices.
+func (f *Format) UnpackSlices(data []byte, offset int) (map[string]any, error) {
+	result, err := f.unpack(data, offset, true, &diag.Warner{Config: &f.config}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return result.Map(), nil
+}
+
+// unpack is php's unpack(), with slices repeated numeric fields are returned as one slice,
+// the bytes of every value are recorded in spans when it is not nil.
+func (f *Format) unpack(data []byte, offset int, slices bool, w *diag.Warner, spans *[]span) (*phparray.Array, error) {
+	if err := w.Replay(f.parseWarnings); err != nil {
+		return nil, err
+	}
 
-		namePos := formatPos
-		name = format[:]
-		argb = repetitions
+	result := phparray.New()
 
-		for formatLen > 0 && format[formatPos] != '/' {
-			formatLen--
-			formatPos++
-		}
+	input := data
+	inputLen := len(input)
+	inputPos := 0
+	version := f.config.Version
 
-		nameLen = formatPos - namePos
+	if offset != 0 && !version.HasUnpackOffset() {
+		return nil, w.Fail(&ArgumentError{Msg: fmt.Sprintf("unpack offset needs php 7.1, the version is %s", version)})
+	}
 
-		if nameLen > 200 {
-			nameLen = 200
-		}
+	if offset < 0 || offset > inputLen {
+		return nil, &InputError{Pos: offset, Need: offset, Have: inputLen, Msg: fmt.Sprintf("offset %d is outside of the input", offset)}
+	}
 
-		switch theType {
-		// Never use any input
-		case 'X':
-			size = -1
-			if repetitions < 0 {
-				log.Printf("type %c: '*' ignored", theType)
-				repetitions = 1
-			}
-		case '@':
-			size = 0
-		case 'a', 'A', 'Z':
-			size = repetitions
-			repetitions = 1
-		case 'h', 'H':
-			size = utils.If(repetitions > 0, (repetitions+(repetitions%2))/2, repetitions)
-			repetitions = 1
-		case 'c', 'C', 'x':
-			size = 1
-		case 's', 'S', 'n', 'v':
-			size = 2
-		case 'i', 'I':
-			size = 4 // size_of(int)
-		case 'l', 'L', 'N', 'V':
-			size = 4
-		case 'q', 'Q', 'J', 'P':
-			size = 8
-		case 'f', 'g', 'G':
-			size = 4 // sizeof(float)
-		case 'd', 'e', 'E':
-			size = 8 // sizeof(double)
-		default:
-			return nil, fmt.Errorf("invalid format type %c\n", theType)
-		}
+	input = input[offset:]
+	inputLen -= offset
 
-		if size != 0 && size != -1 && size < 0 {
-			return nil, fmt.Errorf("type %c: integer overflow", theType)
-		}
+	for fi, field := range f.fields {
+		theType := field.code
+		repetitions := field.repetitions
+		argb := field.argb
+		name := []byte(field.name)
+		namePos := 0
+		nameLen := len(name)
+		size := field.size
 
 		keyPos := 0
+		vals := values{result: result, slice: slices && repetitions != 1 && tags.IsNumeric(theType), floating: tags.IsFloat(theType)}
+		if vals.slice {
+			// no more than the input holds
+			n := (inputLen - inputPos) / size
+			if repetitions >= 0 {
+				n = min(n, repetitions)
+			}
+			vals.grow(n)
+		}
 		// Do actual unpacking
 		for i := 0; i != repetitions; i++ {
 			if size != 0 && size != -1 && math.MaxInt-size+1 < inputPos {
-				return nil, fmt.Errorf("type %c: integer overflow", theType)
+				return nil, &InputError{Code: theType, Pos: offset + inputPos, Need: size, Have: inputLen - inputPos, Msg: "integer overflow"}
 			}
 
 			realName := []byte{}
+			start := inputPos
 			if (inputPos + size) <= inputLen {
-				if repetitions == 1 && nameLen > 0 {
-					// use a part of the formatarg argument directly as the name
-					realName = name[namePos:(namePos + nameLen)]
-				} else {
-					// need to add the 1-based element number to the name
-					buf := make([]byte, 20)
-					end := utils.PrintULongToBuf(buf, uint64(i+1))
-					realName = append(name[namePos:(namePos+nameLen)], buf[end:]...)
-				}
-
 				var key string
-				if len(realName) > 0 {
-					key = string(realName)
-				} else {
-					keyPos++
-					key = fmt.Sprintf("%d", keyPos)
+				if !vals.slice {
+					if repetitions == 1 && nameLen > 0 {
+						// use a part of the formatarg argument directly as the name
+						realName = name[namePos:(namePos + nameLen)]
+					} else {
+						// need to add the 1-based element number to the name
+						buf := make([]byte, 20)
+						end := utils.PrintULongToBuf(buf, uint64(i+1))
+						realName = append(name[namePos:(namePos+nameLen)], buf[end:]...)
+					}
+
+					if len(realName) > 0 {
+						key = string(realName)
+					} else {
+						keyPos++
+						key = fmt.Sprintf("%d", keyPos)
+					}
 				}
 				switch theType {
 				case 'a':
+					if !version.HasZ() {
+						// before php 5.5, a strips trailing NUL bytes
+						result.SetString(key, legacyTrim(input[inputPos:], &size, '\000'))
+						break
+					}
 					length := inputLen - inputPos
 					if size >= 0 && length > size {
 						length = size
 					}
 					size = length
 					s := input[inputPos:(inputPos + length)]
-					result[key] = s
+					result.SetString(key, s)
 				case 'A':
+					if !version.HasZ() {
+						// before php 5.5, A strips trailing spaces only
+						result.SetString(key, legacyTrim(input[inputPos:], &size, ' '))
+						break
+					}
 					var padn byte = '\000'
 					var pads byte = ' '
 					var padt byte = '\t'
@@ -197,10 +201,11 @@ func PHPUnpack(option *Option) (map[string]any, error) {
 							input[inputPos+length] != padl {
 							break
 						}
+						length--
 					}
 
 					s := input[inputPos:(inputPos + length + 1)]
-					result[key] = s
+					result.SetString(key, s)
 				case 'Z':
 					var pad byte = '\000'
 					length := inputLen - inputPos
@@ -218,7 +223,7 @@ func PHPUnpack(option *Option) (map[string]any, error) {
 					}
 
 					s := input[inputPos:(inputPos + length)]
-					result[key] = s
+					result.SetString(key, s)
 				case 'h', 'H':
 					length := (inputLen - inputPos) * 2
 					nibbleShift := utils.If(theType == 'h', 0, 4)
@@ -256,84 +261,70 @@ func PHPUnpack(option *Option) (map[string]any, error) {
 						}
 					}
 
-					result[key] = buf
+					result.SetString(key, buf)
 				case 'c', 'C':
 					x := input[inputPos]
 					if theType == 'c' {
 						// signed
-						result[key] = int64(x)
+						vals.int(key, int64(int8(x)))
 					} else {
-						result[key] = int64(x)
+						vals.int(key, int64(x))
 					}
 				case 's', 'S', 'n', 'v':
-					x := *(*uint16)(unsafe.Pointer(&input[inputPos]))
+					x := f.platform.OrderOf(theType).Uint16(input[inputPos:])
 					if theType == 's' {
-						result[key] = int64(x)
-					} else if (theType == 'n' && utils.IsLittleEndian()) || (theType == 'v' && !utils.IsLittleEndian()) {
-						result[key] = int64(utils.PhpPackReverseInt16(x))
+						vals.int(key, int64(int16(x)))
 					} else {
-						result[key] = int64(x)
+						vals.int(key, int64(x))
 					}
 				case 'i', 'I':
+					x := utils.GetUint(f.platform.ByteOrder(), input[inputPos:], size)
 					if theType == 'i' {
-						result[key] = int64(*(*int)(unsafe.Pointer(&input[inputPos])))
+						// sign extend from sizeof(int)
+						shift := 64 - 8*size
+						vals.int(key, f.long(int64(x<<shift)>>shift))
 					} else {
-						result[key] = int64(*(*uint)(unsafe.Pointer(&input[inputPos])))
+						vals.int(key, f.long(int64(x)))
 					}
 				case 'l', 'L', 'N', 'V':
-					x := *(*uint32)(unsafe.Pointer(&input[inputPos]))
+					x := f.platform.OrderOf(theType).Uint32(input[inputPos:])
 					if theType == 'l' {
-						result[key] = int64(x)
-					} else if (theType == 'N' && utils.IsLittleEndian()) || (theType == 'V' && !utils.IsLittleEndian()) {
-						result[key] = utils.PhpPackReverseInt32(x)
+						vals.int(key, int64(int32(x)))
 					} else {
-						result[key] = int64(x) // result[key] = x
+						vals.int(key, f.long(int64(x)))
 					}
 				case 'q', 'Q', 'J', 'P':
-					x := *(*uint64)(unsafe.Pointer(&input[inputPos]))
-					if theType == 'q' {
-						result[key] = int64(x)
-					} else if (theType == 'J' && utils.IsLittleEndian()) || (theType == 'P' && !utils.IsLittleEndian()) {
-						result[key] = int64(utils.PhpPackReverseInt64(x))
-					} else {
-						result[key] = int64(x)
-					}
+					x := f.platform.OrderOf(theType).Uint64(input[inputPos:])
+					vals.int(key, int64(x))
 				case 'f', 'g', 'G':
-					if theType == 'g' {
-						result[key] = float64(utils.PhpPackParseFloat(true, input[inputPos:(inputPos+4)]))
-					} else if theType == 'G' {
-						result[key] = float64(utils.PhpPackParseFloat(false, input[inputPos:(inputPos+4)]))
-					} else {
-						var v []byte
-						copy(v, input[inputPos:(inputPos+4)])
-						result[key] = float64(*(*float32)(unsafe.Pointer(&v[0])))
-					}
+					x := f.platform.OrderOf(theType).Uint32(input[inputPos:])
+					vals.float(key, float64(math.Float32frombits(x)))
 				case 'd', 'e', 'E':
-					if theType == 'e' {
-						result[key] = utils.PhpPackParseDouble(true, input[inputPos:(inputPos+8)])
-					} else if theType == 'E' {
-						result[key] = utils.PhpPackParseDouble(false, input[inputPos:(inputPos+8)])
-					} else {
-						var v []byte
-						copy(v, input[inputPos:(inputPos+8)])
-						result[key] = *(*float64)(unsafe.Pointer(&v[0]))
-					}
-				case 'x':
-					log.Printf("format x: do nothing")
+					x := f.platform.OrderOf(theType).Uint64(input[inputPos:])
+					vals.float(key, math.Float64frombits(x))
 				case 'X':
 					if inputPos < size {
 						inputPos = -size
 						i = repetitions - 1
 
 						if repetitions >= 0 {
-							log.Printf("type %c: outside of string", theType)
+							if err := w.Warn(theType, "outside of string"); err != nil {
+								return nil, err
+							}
 						}
+					} else {
+						// the remaining repetitions step back one byte each, stopping at 0,
+						// take them at once
+						inputPos = utils.Max(0, inputPos-(repetitions-i-1))
+						i = repetitions - 1
 					}
 				case '@':
 					if repetitions <= inputLen {
 						inputPos = repetitions
 					} else {
-						log.Printf("type %c: outside of string", theType)
+						if err := w.Warn(theType, "outside of string"); err != nil {
+							return nil, err
+						}
 					}
 					i = repetitions - 1
 				}
@@ -341,22 +332,104 @@ func PHPUnpack(option *Option) (map[string]any, error) {
 				inputPos += size
 				if inputPos < 0 {
 					if size != -1 {
-						log.Printf("type %c: outside of string", theType)
+						if err := w.Warn(theType, "outside of string"); err != nil {
+							return nil, err
+						}
 					}
 					inputPos = 0
 				}
+				if spans != nil {
+					addSpan(spans, result, span{field: fi, code: theType, key: key, offset: offset + start, size: inputPos - start})
+				}
 			} else if repetitions < 0 {
 				break
 			} else {
-				return nil, fmt.Errorf("type %c: not enough input, need %d, have %d", theType, size, inputLen-inputPos)
+				if spans != nil {
+					*spans = append(*spans, span{field: fi, code: theType, offset: offset + inputPos, size: inputLen - inputPos, need: size})
+				}
+				return nil, &InputError{Code: theType, Pos: offset + inputPos, Need: size, Have: inputLen - inputPos, Msg: "not enough input"}
 			}
 		}
 
-		if formatLen > 0 {
-			formatLen--
-			formatPos++
+		if vals.slice {
+			vals.store(field.name)
 		}
 	}
 
 	return result, nil
 }
+
+// values stores the numbers of a field: one key each, or with Option.Slices one slice under the field name.
+type values struct {
+	result *phparray.Array
+	slice  bool
+	ints   []int64
+	floats []float64
+	// floating is set for the float codes, which fill floats
+	floating bool
+}
+
+// grow makes room for n numbers.
+func (v *values) grow(n int) {
+	if v.floating {
+		v.floats = make([]float64, 0, n)
+	} else {
+		v.ints = make([]int64, 0, n)
+	}
+}
+
+func (v *values) int(key string, n int64) {
+	if v.slice {
+		v.ints = append(v.ints, n)
+		return
+	}
+	v.result.SetString(key, n)
+}
+
+func (v *values) float(key string, f float64) {
+	if v.slice {
+		v.floats = append(v.floats, f)
+		return
+	}
+	v.result.SetString(key, f)
+}
+
+// store sets the slice under name, "1" for an unnamed field like its first key.
+// A field that read no value sets no key, php sets none either.
+func (v *values) store(name string) {
+	if len(v.ints) == 0 && len(v.floats) == 0 {
+		return
+	}
+	if name == "" {
+		name = "1"
+	}
+	if v.floating {
+		v.result.SetString(name, v.floats)
+	} else {
+		v.result.SetString(name, v.ints)
+	}
+}
+
+// legacyTrim is the a and A unpacking of php before 5.5: take size bytes, or the rest of
+// the input for '*', and strip the trailing pad bytes.
+func legacyTrim(input []byte, size *int, pad byte) []byte {
+	length := len(input)
+	if *size >= 0 && length > *size {
+		length = *size
+	}
+	*size = length
+
+	for length > 0 && input[length-1] == pad {
+		length--
+	}
+	return input[:length]
+}
+
+// long converts an unpacked integer to the platform's zend_long,
+// a 32-bit php wraps unsigned 32-bit values to negative integers.
+func (f *Format) long(v int64) int64 {
+	if !f.platform.Is64Bit {
+		return int64(int32(v))
+	}
+	return v
+}
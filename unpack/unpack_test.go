@@ -3,6 +3,7 @@ package unpack
 import (
 	"fmt"
 	"github.com/xycczZ/php_pack/pack"
+	"github.com/xycczZ/php_pack/target"
 	"log"
 	"reflect"
 	"strconv"
@@ -133,3 +134,103 @@ func sliceEq[T comparable](a, b []T) bool {
 
 	return true
 }
+
+func TestUnpackSlices(t *testing.T) {
+	data, err := pack.PHPPack("Cn3a2g*", 7, 1, 2, 3, "ab", 1.5, -2)
+	if err != nil {
+		t.Errorf("pack failed: %v\n", err)
+		return
+	}
+
+	cases := []struct {
+		Format   string
+		Expected map[string]any
+	}{
+		{"Cid/n3vals/a2s/g*f", map[string]any{"id": int64(7), "vals": []int64{1, 2, 3}, "s": []byte("ab"), "f": []float64{1.5, -2}}},
+		{"C1id/n*vals", map[string]any{"id": int64(7), "vals": []int64{0x0001, 0x0002, 0x0003, 0x6162, 0x0000, 0xc03f, 0x0000, 0x00c0}}},
+		{"x/n3/N0none/x2/g2f", map[string]any{"1": []int64{1, 2, 3}, "f": []float64{1.5, -2}}},
+		{"@13/g*f", map[string]any{"f": []float64{-2}}},
+		{"@17/C*rest", map[string]any{}},
+		{"a*s", map[string]any{"s": data}},
+		{"H4hex/a2", map[string]any{"hex": []byte("0700"), "1": []byte("\x01\x00")}},
+	}
+
+	for _, c := range cases {
+		option := NewOption(c.Format, data)
+		option.Slices = true
+		actual, err := PHPUnpack(option)
+		if err != nil {
+			t.Errorf("unpack %s failed: %v\n", c.Format, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, c.Expected) {
+			t.Errorf("unpack %s error, expected: %v, actual: %v\n", c.Format, c.Expected, actual)
+		}
+
+		f, err := Compile(c.Format)
+		if err != nil {
+			t.Errorf("compile %s failed: %v\n", c.Format, err)
+			continue
+		}
+		if actual, err := f.UnpackSlices(data, 0); err != nil || !reflect.DeepEqual(actual, c.Expected) {
+			t.Errorf("unpack slices %s error, expected: %v, actual: %v %v\n", c.Format, c.Expected, actual, err)
+		}
+	}
+
+	// the default stays php's numbered keys
+	actual, err := PHPUnpack(NewOption("Cid/n3vals", data))
+	expected := map[string]any{"id": int64(7), "vals1": int64(1), "vals2": int64(2), "vals3": int64(3)}
+	if err != nil || !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unpack error, expected: %v, actual: %v %v\n", expected, actual, err)
+	}
+
+	option := NewOption("n20vals", data)
+	option.Slices = true
+	if _, err := PHPUnpack(option); err == nil {
+		t.Errorf("unpack n20vals error, expected: not enough input\n")
+	}
+}
+
+// TestUnpackPositions covers names, repetitions, x, X, @ and odd h/H lengths as unpack.c implements them,
+// the result is compared in order with the typed values of testdata/phpt.
+func TestUnpackPositions(t *testing.T) {
+	cases := []struct {
+		Format string
+		Input  string
+		Result [][2]string
+	}{
+		{"c2chars/nint", "\x04\x03\x12\x34", [][2]string{{"chars1", "i:4"}, {"chars2", "i:3"}, {"int", "i:4660"}}},
+		{"C12", "\x01\x02\x03\x04\x05\x06\x07\x08\x09\x0a\x0b\x0c", [][2]string{{"1", "i:1"}, {"2", "i:2"}, {"3", "i:3"}, {"4", "i:4"}, {"5", "i:5"}, {"6", "i:6"}, {"7", "i:7"}, {"8", "i:8"}, {"9", "i:9"}, {"10", "i:10"}, {"11", "i:11"}, {"12", "i:12"}}},
+		{"C*n", "\x01\x02\x03\x04\x05\x06\x07\x08\x09\x0a\x0b\x0c", [][2]string{{"n1", "i:1"}, {"n2", "i:2"}, {"n3", "i:3"}, {"n4", "i:4"}, {"n5", "i:5"}, {"n6", "i:6"}, {"n7", "i:7"}, {"n8", "i:8"}, {"n9", "i:9"}, {"n10", "i:10"}, {"n11", "i:11"}, {"n12", "i:12"}}},
+		{"Ca/Cb/Ca", "\x01\x02\x03", [][2]string{{"a", "i:3"}, {"b", "i:2"}}},
+		{"c/c", "\x01\xff", [][2]string{{"1", "i:-1"}}},
+		{"c2/C", "\x01\xff\x02", [][2]string{{"1", "i:2"}, {"2", "i:-1"}}},
+		{"Cx/@4/Cy/X2/Cz", "\x01\x02\x03\x04\x05", [][2]string{{"x", "i:1"}, {"y", "i:5"}, {"z", "i:4"}}},
+		{"Ca/x2/Cb", "\x01\x02\x03\x04", [][2]string{{"a", "i:1"}, {"b", "i:4"}}},
+		{"Ca/X/Cb", "\x01", [][2]string{{"a", "i:1"}, {"b", "i:1"}}},
+		{"Ca/X2/Cb", "\x01\x02", [][2]string{{"a", "i:1"}, {"b", "i:1"}}},
+		{"@1/Ca", "\x01\x02", [][2]string{{"a", "i:2"}}},
+		{"@0/Ca", "\x01\x02", [][2]string{{"a", "i:1"}}},
+		{"h3", "\x21\x43", [][2]string{{"1", "x:313233"}}},
+		{"H3", "\x21\x43", [][2]string{{"1", "x:323134"}}},
+		{"h*", "\x21\x43", [][2]string{{"1", "x:31323334"}}},
+		{"H1a/H1b", "\x21\x43", [][2]string{{"a", "x:32"}, {"b", "x:34"}}},
+		{"h0", "\x21", [][2]string{{"1", "x:"}}},
+		{"sa/lb/qc", "\xfe\xff\xfe\xff\xff\xff\xfe\xff\xff\xff\xff\xff\xff\xff", [][2]string{{"a", "i:-2"}, {"b", "i:-2"}, {"c", "i:-2"}}},
+		{"Sa/Lb/Qc", "\xfe\xff\xfe\xff\xff\xff\xfe\xff\xff\xff\xff\xff\xff\xff", [][2]string{{"a", "i:65534"}, {"b", "i:4294967294"}, {"c", "i:-2"}}},
+		{"a2a/A2b/Z2c", "\x61\x00\x61\x20\x61\x00\x62", [][2]string{{"a", "x:6100"}, {"b", "x:61"}, {"c", "x:61"}}},
+	}
+
+	for _, c := range cases {
+		option := NewOption(c.Format, []byte(c.Input))
+		option.Platform = target.AMD64
+		r, err := PHPUnpackArray(option)
+		if err != nil {
+			t.Errorf("unpack %s failed: %v\n", c.Format, err)
+			continue
+		}
+		if !resultEq(t, r.Keys(), r.Values(), c.Result) {
+			t.Errorf("unpack %s error, expected: %v, actual: %v\n", c.Format, c.Result, r.Map())
+		}
+	}
+}
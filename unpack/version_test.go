@@ -0,0 +1,77 @@
+package unpack
+
+import (
+	"errors"
+	"github.com/xycczZ/php_pack/target"
+	"testing"
+)
+
+func TestVersions(t *testing.T) {
+	type unpackCase struct {
+		Format string
+		Input  string
+		Offset int
+		// Expected is nil when the version rejects the call
+		Expected map[string]any
+	}
+
+	input := "ab \000\000 \000"
+	versions := []struct {
+		Version target.Version
+		Cases   []unpackCase
+	}{
+		{target.PHP54, []unpackCase{
+			{"a*", input, 0, map[string]any{"1": []byte("ab \000\000 ")}},
+			{"A*", input, 0, map[string]any{"1": []byte(input)}},
+			{"A3", "ab  ", 0, map[string]any{"1": []byte("ab")}},
+			{"Z*", input, 0, nil},
+			{"J", "\000\000\000\000\000\000\000\001", 0, nil},
+			{"C", "ab", 1, nil},
+		}},
+		{target.PHP55, []unpackCase{
+			{"a*", input, 0, map[string]any{"1": []byte(input)}},
+			{"A*", input, 0, map[string]any{"1": []byte("ab")}},
+			{"Z*", input, 0, map[string]any{"1": []byte("ab ")}},
+			{"J", "\000\000\000\000\000\000\000\001", 0, nil},
+		}},
+		{target.PHP56, []unpackCase{
+			{"J", "\000\000\000\000\000\000\000\001", 0, nil},
+		}},
+		{target.Version{Major: 5, Minor: 6, Patch: 3}, []unpackCase{
+			{"P", "\001\000\000\000\000\000\000\000", 0, map[string]any{"1": int64(1)}},
+		}},
+		{target.PHP70, []unpackCase{
+			{"C", "ab", 1, nil},
+			{"G", "\x3f\xc0\x00\x00", 0, nil},
+		}},
+		{target.PHP71, []unpackCase{
+			{"C", "ab", 1, map[string]any{"1": int64('b')}},
+			{"G", "\x3f\xc0\x00\x00", 0, nil},
+		}},
+		{target.PHP80, []unpackCase{
+			{"C", "ab", 1, map[string]any{"1": int64('b')}},
+			{"y", "ab", 0, nil},
+		}},
+	}
+
+	for _, v := range versions {
+		for _, c := range v.Cases {
+			option := &Option{Format: c.Format, Val: []byte(c.Input), Offset: c.Offset}
+			option.Version = v.Version
+			r, err := PHPUnpack(option)
+			if c.Expected == nil {
+				if err == nil || !(errors.Is(err, ErrFormat) || errors.Is(err, ErrArgument)) {
+					t.Errorf("php %s should reject %s, err: %v\n", v.Version, c.Format, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("php %s should accept %s, err: %v\n", v.Version, c.Format, err)
+				continue
+			}
+			if !mapEq(r, c.Expected) {
+				t.Errorf("php %s unpack %s error, expected: %q, actual: %q\n", v.Version, c.Format, c.Expected, r)
+			}
+		}
+	}
+}